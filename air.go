@@ -0,0 +1,258 @@
+package cinnabot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usdevs/cinnabot/model"
+	"github.com/usdevs/cinnabot/weather"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// AirAlertInterval is how often CheckAirAlerts should be run. The PSI is updated hourly.
+const AirAlertInterval = 15 * time.Minute
+
+// regionName returns the name of a region for display, eg. "the West region"
+func regionName(region string) string {
+	if region == weather.National {
+		return "Singapore"
+	}
+	return "the " + strings.Title(region) + " region"
+}
+
+// noPSIStr is the reply to /air when there is no PSI reading for the region or the whole of Singapore
+const noPSIStr = "🤖: PSI is unavailable right now, please try again later."
+
+// formatAir formats the air quality readings for a region. The PM2.5 and UV readings are left
+// out if they could not be fetched.
+func formatAir(region string, psi, pm25 weather.RegionReadings, uv weather.UV) string {
+	value, ok := psi.Values[region]
+	if !ok {
+		region = weather.National
+		if value, ok = psi.Values[region]; !ok {
+			return noPSIStr
+		}
+	}
+	band := weather.PSIBand(value)
+	text := fmt.Sprintf("🤖: *Air quality in %s*\n", regionName(region))
+	text += fmt.Sprintf("🌫 PSI (24h): *%.0f* · %s\n", value, band.Name)
+	advice := band.Advice
+
+	if value, ok := pm25.Values[region]; ok {
+		text += fmt.Sprintf("😷 PM2.5 (1h): *%.0f* µg/m³ · %s\n", value, weather.PM25Band(value).Name)
+	}
+	if !uv.Timestamp.IsZero() {
+		uvBand := weather.UVBand(uv.Index)
+		text += fmt.Sprintf("☀️ UV index: *%.0f* · %s\n", uv.Index, uvBand.Name)
+		if uv.Index >= 3 {
+			advice += " " + uvBand.Advice
+		}
+	}
+	text += "\n" + advice + "\n\nLast updated: " + psi.Timestamp.Format("3pm")
+	return text
+}
+
+// makeAirKeyboard returns the buttons under the air quality readings for a region
+func makeAirKeyboard(region string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Refresh", "//air_refresh "+region),
+		tgbotapi.NewInlineKeyboardButtonData("🔔 PSI alert", "//air_alerts "+region),
+	))
+}
+
+// airResponse fetches the air quality readings for a region
+func (cb *Cinnabot) airResponse(region string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), weatherRequestTime)
+	defer cancel()
+
+	psi, err := cb.weather.PSI(ctx)
+	if err != nil {
+		return "", err
+	}
+	pm25, err := cb.weather.PM25(ctx)
+	if err != nil {
+		cb.log.Printf("cannot get PM2.5: %s", err)
+	}
+	uv, err := cb.weather.UV(ctx)
+	if err != nil {
+		cb.log.Printf("cannot get UV index: %s", err)
+	}
+	return formatAir(region, psi, pm25, uv), nil
+}
+
+// Air shows the PSI, PM2.5 and UV index for the region nearest to the user
+func (cb *Cinnabot) Air(msg *message) {
+	cb.useSavedPlace(msg)
+	//Check if air was sent with location, if not reply with markup
+	if len(msg.Args) == 0 || !cb.CheckArgCmdPair("/air", msg.Args) {
+		opt1 := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("Cinnamon"))
+		opt2B := tgbotapi.NewKeyboardButton("Here")
+		opt2B.RequestLocation = true
+		opt2 := tgbotapi.NewKeyboardButtonRow(opt2B)
+
+		options := cb.locationKeyboard(msg.From.ID, opt1, opt2)
+
+		replyMsg := tgbotapi.NewMessage(msg.Chat.ID, "🤖: Where are you?\n\n")
+		replyMsg.ReplyMarkup = options
+		cb.SendMessage(replyMsg)
+		return
+	}
+
+	//Default loc: Cinnamon
+	loc := cinnamonLoc
+	if msg.Location != nil {
+		loc = *msg.Location
+	}
+	region := weather.Region(loc.Latitude, loc.Longitude)
+
+	text, err := cb.airResponse(region)
+	if err != nil {
+		cb.log.Printf("cannot get air quality: %s", err)
+		cb.SendTextMessage(int(msg.Chat.ID), weatherErrorStr(err))
+		return
+	}
+	cb.SendMessage(NewMessageWithButton(text, makeAirKeyboard(region), msg.Chat.ID))
+}
+
+// AirRefresh updates the air quality readings in a message
+func (cb *Cinnabot) AirRefresh(qry *Callback) {
+	if len(qry.Args) < 1 {
+		return
+	}
+	text, err := cb.airResponse(qry.Args[0])
+	if err != nil {
+		cb.log.Printf("cannot get air quality: %s", err)
+		text = weatherErrorStr(err)
+	}
+	cb.SendMessage(EditedMessageWithButton(text, makeAirKeyboard(qry.Args[0]), qry.ChatID, qry.MsgID))
+}
+
+// airAlertStatus describes the user's PSI alert
+func airAlertStatus(alert model.AirAlert, ok bool) string {
+	if !ok {
+		return "You don't have a PSI alert."
+	}
+	return fmt.Sprintf("Your alert is for when the 24h PSI in %s reaches %d (%s).",
+		regionName(alert.Region), alert.Threshold, weather.PSIBand(float64(alert.Threshold)).Name)
+}
+
+// AirAlerts shows the PSI thresholds a user can be alerted at, for the region in the message
+func (cb *Cinnabot) AirAlerts(qry *Callback) {
+	if len(qry.Args) < 1 {
+		return
+	}
+	region := qry.Args[0]
+	alert, ok := cb.db.UserAirAlert(qry.From.ID)
+	text := fmt.Sprintf("🤖: %s\n\nWhen should I message you about the PSI in %s?", airAlertStatus(alert, ok), regionName(region))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	// Alerts for the first band would always fire
+	for _, band := range weather.PSIBands()[1:] {
+		label := fmt.Sprintf("%s (%.0f+)", band.Name, band.Min)
+		data := fmt.Sprintf("//air_alert_set %s %.0f", region, band.Min)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(label, data)))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔕 Turn off", "//air_alert_set "+region+" 0"),
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", "//air_refresh "+region),
+	))
+	cb.SendMessage(EditedMessageWithButton(text, tgbotapi.NewInlineKeyboardMarkup(rows...), qry.ChatID, qry.MsgID))
+}
+
+// AirAlertSet sets or removes the user's PSI alert. A threshold of 0 removes it.
+func (cb *Cinnabot) AirAlertSet(qry *Callback) {
+	if len(qry.Args) < 2 {
+		return
+	}
+	region := qry.Args[0]
+	threshold, err := strconv.Atoi(qry.Args[1])
+	if err != nil {
+		return
+	}
+
+	if threshold <= 0 {
+		err = cb.db.DeleteAirAlert(qry.From.ID)
+	} else {
+		err = cb.db.SaveAirAlert(qry.From.ID, region, threshold)
+	}
+	if err != nil {
+		cb.log.Printf("cannot update air alert: %s", err)
+		cb.SendTextMessage(int(qry.ChatID), "🤖: Sorry, I couldn't update your alert. Please try again later.")
+		return
+	}
+
+	alert, ok := cb.db.UserAirAlert(qry.From.ID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", "//air_refresh "+region),
+	))
+	cb.SendMessage(EditedMessageWithButton("🤖: "+airAlertStatus(alert, ok), keyboard, qry.ChatID, qry.MsgID))
+}
+
+// airAlertKey identifies an alert, so that changing the threshold or region starts afresh
+type airAlertKey struct {
+	UserID    int
+	Region    string
+	Threshold int
+}
+
+// airAlerts remembers whether the PSI was at or above the threshold of each alert at the last
+// check, so that users are alerted once when it rises past their threshold rather than every hour
+// it stays above it.
+type airAlerts struct {
+	mu    sync.Mutex
+	above map[airAlertKey]bool
+}
+
+func newAirAlerts() *airAlerts {
+	return &airAlerts{above: make(map[airAlertKey]bool)}
+}
+
+// due records the latest PSI readings and returns the alerts whose region has just reached
+// their threshold
+func (aa *airAlerts) due(alerts []model.AirAlert, psi map[string]float64) []model.AirAlert {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+
+	fired := make([]model.AirAlert, 0)
+	for _, alert := range alerts {
+		value, ok := psi[alert.Region]
+		if !ok {
+			continue
+		}
+		key := airAlertKey{alert.UserID, alert.Region, alert.Threshold}
+		wasAbove, known := aa.above[key]
+		aa.above[key] = value >= float64(alert.Threshold)
+		if known && !wasAbove && aa.above[key] {
+			fired = append(fired, alert)
+		}
+	}
+	return fired
+}
+
+// CheckAirAlerts messages users when the PSI in their region reaches their threshold
+func (cb *Cinnabot) CheckAirAlerts() {
+	alerts := cb.db.AirAlerts()
+	if len(alerts) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), weatherRequestTime)
+	defer cancel()
+	psi, err := cb.weather.PSI(ctx)
+	if err != nil {
+		cb.log.Printf("cannot check air alerts: %s", err)
+		return
+	}
+
+	for _, alert := range cb.airAlerts.due(alerts, psi.Values) {
+		value := psi.Values[alert.Region]
+		band := weather.PSIBand(value)
+		text := fmt.Sprintf("🌫 The 24h PSI in %s is now *%.0f* (%s). %s\n\n/air for details",
+			regionName(alert.Region), value, band.Name, band.Advice)
+		cb.SendTextMessage(alert.UserID, text)
+	}
+}
@@ -21,6 +21,10 @@ func (mb *mockBot) GetUpdatesChan(config tgbotapi.UpdateConfig) (tgbotapi.Update
 	return nil, nil
 }
 
+func (mb *mockBot) GetFileDirectURL(fileID string) (string, error) {
+	return "", nil
+}
+
 func (mb *mockBot) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
 	args := mb.Called(c)
 	return tgbotapi.Message{}, args.Error(0)
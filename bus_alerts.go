@@ -0,0 +1,367 @@
+package cinnabot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usdevs/cinnabot/transit"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+const (
+	maxAlertsPerUser  = 3
+	busAlertTTL       = time.Hour        // alerts which have not fired by then are dropped
+	busAlertCheckTime = 10 * time.Second // deadline for fetching timings when checking alerts
+)
+
+// BusAlertInterval is how often CheckBusAlerts should be run
+const BusAlertInterval = 30 * time.Second
+
+// Number of minutes before a bus arrives that a user can ask to be alerted
+var busAlertThresholds = []int{2, 5, 10, 15}
+
+var errTooManyAlerts = errors.New("too many bus alerts")
+
+// busAlert is a request to message a user when a bus is some minutes away from a stop
+type busAlert struct {
+	UserID   int
+	ChatID   int64
+	Kind     string // nusStop or publicStop
+	Code     string
+	StopName string
+	Service  string
+	Minutes  int
+	Expiry   time.Time
+}
+
+func (a busAlert) String() string {
+	return fmt.Sprintf("🚍%s at %s, %d mins away", a.Service, a.StopName, a.Minutes)
+}
+
+// busAlerts holds the active alerts of every user in memory. Alerts are short-lived,
+// so they are not persisted across restarts.
+type busAlerts struct {
+	mu     sync.Mutex
+	byUser map[int][]busAlert
+}
+
+func newBusAlerts() *busAlerts {
+	return &busAlerts{byUser: make(map[int][]busAlert)}
+}
+
+// add adds an alert, replacing any existing alert by the user for the same service at the same stop.
+// Returns errTooManyAlerts if the user already has the maximum number of alerts.
+func (ba *busAlerts) add(alert busAlert) error {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+
+	alerts := ba.byUser[alert.UserID]
+	for i, a := range alerts {
+		if a.Kind == alert.Kind && a.Code == alert.Code && a.Service == alert.Service {
+			alerts[i] = alert
+			return nil
+		}
+	}
+	if len(alerts) >= maxAlertsPerUser {
+		return errTooManyAlerts
+	}
+	ba.byUser[alert.UserID] = append(alerts, alert)
+	return nil
+}
+
+// list returns the active alerts of the user
+func (ba *busAlerts) list(userID int) []busAlert {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+	return append([]busAlert(nil), ba.byUser[userID]...)
+}
+
+// all returns the active alerts of every user
+func (ba *busAlerts) all() []busAlert {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+	alerts := make([]busAlert, 0)
+	for _, userAlerts := range ba.byUser {
+		alerts = append(alerts, userAlerts...)
+	}
+	return alerts
+}
+
+// remove removes the alert, if it is still active
+func (ba *busAlerts) remove(alert busAlert) {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+
+	alerts := ba.byUser[alert.UserID]
+	for i, a := range alerts {
+		if a == alert {
+			alerts = append(alerts[:i], alerts[i+1:]...)
+			break
+		}
+	}
+	if len(alerts) == 0 {
+		delete(ba.byUser, alert.UserID)
+	} else {
+		ba.byUser[alert.UserID] = alerts
+	}
+}
+
+// clear removes all of the user's alerts
+func (ba *busAlerts) clear(userID int) {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+	delete(ba.byUser, userID)
+}
+
+// alertDue returns the number of minutes until the next bus of the alert's service, and whether
+// that is within the alert's threshold
+func alertDue(alert busAlert, arrivals []transit.Arrival, now time.Time) (int, bool) {
+	for _, arrival := range arrivals {
+		if arrival.Service != alert.Service || len(arrival.Buses) == 0 {
+			continue
+		}
+		mins := arrival.Buses[0].MinutesAway(now)
+		return mins, mins <= alert.Minutes
+	}
+	return 0, false
+}
+
+// CheckBusAlerts messages users whose buses are arriving and drops expired alerts
+func (cb *Cinnabot) CheckBusAlerts() {
+	alerts := cb.busAlerts.all()
+	if len(alerts) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), busAlertCheckTime)
+	defer cancel()
+
+	// Alerts at the same stop are served from the client's cache
+	now := time.Now()
+	for _, alert := range alerts {
+		if now.After(alert.Expiry) {
+			cb.busAlerts.remove(alert)
+			cb.SendTextMessage(int(alert.ChatID), "🤖: Your alert for "+alert.String()+" has expired.")
+			continue
+		}
+		arrivals, err := cb.busClient(alert.Kind).Arrivals(ctx, alert.Code)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		if mins, due := alertDue(alert, arrivals, now); due {
+			cb.busAlerts.remove(alert)
+			cb.SendTextMessage(int(alert.ChatID), fmt.Sprintf("🔔 Bus *%s* is arriving at *%s* in %d mins!", alert.Service, alert.StopName, mins))
+		}
+	}
+}
+
+// makeRefreshRow returns the row with the refresh and "Alert me" buttons for a bus timing message.
+// refresh is the callback data of the refresh button, which the "Alert me" button uses to find the
+// stops in the message again. The "Alert me" button is left out if its data would be too long.
+func makeRefreshRow(refresh string) []tgbotapi.InlineKeyboardButton {
+	row := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Refresh", refresh))
+	if alert := "//busalert " + strings.TrimPrefix(refresh, "//"); len(alert) <= maxCallbackDataLen {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("🔔 Alert me", alert))
+	}
+	return row
+}
+
+// alertStop is a stop which a bus alert can be set at
+type alertStop struct {
+	Kind string
+	Code string
+	Name string
+}
+
+// alertStops returns the stops shown in a bus timing message, given the arguments of its refresh button
+func (cb *Cinnabot) alertStops(userID int, refresh []string) []alertStop {
+	if len(refresh) == 0 {
+		return nil
+	}
+	stops := make([]alertStop, 0)
+	switch refresh[0] {
+	case "nusbus_refresh":
+		for _, stop := range nusBusStopLocations[strings.Join(refresh[1:], " ")] {
+			stops = append(stops, alertStop{nusStop, stop, stop})
+		}
+	case "nusbus_loc_refresh", "publicbus_refresh":
+		if len(refresh) < 3 {
+			return nil
+		}
+		long, _ := strconv.ParseFloat(refresh[1], 64)
+		lat, _ := strconv.ParseFloat(refresh[2], 64)
+		loc := tgbotapi.Location{Longitude: long, Latitude: lat}
+		kind, n := nusStop, maxNearbyNUSStops
+		if refresh[0] == "publicbus_refresh" {
+			kind, n = publicStop, maxNearbyPublicStops
+		}
+		for _, stop := range nearestStops(cb.busClient(kind), loc, n, cb.keys.NearbyStopRadius) {
+			stops = append(stops, alertStop{kind, stop.Code, stop.Name})
+		}
+	case "publicbus_stop":
+		code := strings.Join(refresh[1:], " ")
+		stops = append(stops, alertStop{publicStop, code, stopName(cb.publicBus, code)})
+	case "mybus_refresh":
+		for _, fav := range cb.db.FavouriteStops(userID) {
+			stops = append(stops, alertStop{fav.Kind, fav.Code, fav.Name})
+		}
+	}
+	return stops
+}
+
+// BusAlert handles the "Alert me" button on bus timing messages by asking which stop to set the alert at
+func (cb *Cinnabot) BusAlert(qry *Callback) {
+	stops := cb.alertStops(qry.From.ID, qry.Args)
+	if len(stops) == 0 {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while setting a bus alert")
+		return
+	}
+	if len(stops) == 1 {
+		cb.sendAlertServices(qry.ChatID, stops[0].Kind, stops[0].Code)
+		return
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(stops))
+	for _, stop := range stops {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(stop.Name, "//busalert_stop "+stop.Kind+" "+stop.Code),
+		))
+	}
+	cb.SendMessage(NewMessageWithButton("🤖: Which stop are you waiting at?", tgbotapi.NewInlineKeyboardMarkup(rows...), qry.ChatID))
+}
+
+// BusAlertStop handles the choice of stop for a bus alert
+func (cb *Cinnabot) BusAlertStop(qry *Callback) {
+	if len(qry.Args) < 2 {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while setting a bus alert")
+		return
+	}
+	cb.sendAlertServices(qry.ChatID, qry.Args[0], strings.Join(qry.Args[1:], " "))
+}
+
+// sendAlertServices asks which of the services currently operating at the stop to set an alert for
+func (cb *Cinnabot) sendAlertServices(chatID int64, kind, code string) {
+	ctx, cancel := context.WithTimeout(context.Background(), busAlertCheckTime)
+	defer cancel()
+	arrivals, err := cb.busClient(kind).Arrivals(ctx, code)
+	if err != nil {
+		log.Print(err)
+		cb.SendTextMessage(int(chatID), "🤖: Bus timings are unavailable right now, please try again later.")
+		return
+	}
+
+	services := make([]string, 0, len(arrivals))
+	for _, arrival := range arrivals {
+		if len(arrival.Buses) > 0 {
+			services = append(services, arrival.Service)
+		}
+	}
+	if len(services) == 0 {
+		cb.SendTextMessage(int(chatID), "🤖: No buses are operating at this stop right now.")
+		return
+	}
+	sort.Strings(services)
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0)
+	var row []tgbotapi.InlineKeyboardButton
+	for _, service := range services {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(service, "//busalert_svc "+kind+" "+service+" "+code))
+		if len(row) == 4 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	text := "🤖: Which bus at *" + stopName(cb.busClient(kind), code) + "* should I alert you about?"
+	cb.SendMessage(NewMessageWithButton(text, tgbotapi.NewInlineKeyboardMarkup(rows...), chatID))
+}
+
+// BusAlertService handles the choice of service for a bus alert by asking how early to alert the user
+func (cb *Cinnabot) BusAlertService(qry *Callback) {
+	if len(qry.Args) < 3 {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while setting a bus alert")
+		return
+	}
+	kind, service, code := qry.Args[0], qry.Args[1], strings.Join(qry.Args[2:], " ")
+
+	var row []tgbotapi.InlineKeyboardButton
+	for _, mins := range busAlertThresholds {
+		data := fmt.Sprintf("//busalert_set %s %s %d %s", kind, service, mins, code)
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d mins", mins), data))
+	}
+	text := "🤖: How many minutes before bus *" + service + "* arrives should I alert you?"
+	cb.SendMessage(EditedMessageWithButton(text, tgbotapi.NewInlineKeyboardMarkup(row), qry.ChatID, qry.MsgID))
+}
+
+// BusAlertSet handles the choice of threshold for a bus alert, and starts watching for the bus
+func (cb *Cinnabot) BusAlertSet(qry *Callback) {
+	if len(qry.Args) < 4 {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while setting a bus alert")
+		return
+	}
+	mins, err := strconv.Atoi(qry.Args[2])
+	if err != nil {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while setting a bus alert")
+		return
+	}
+	kind, code := qry.Args[0], strings.Join(qry.Args[3:], " ")
+	alert := busAlert{
+		UserID:   qry.From.ID,
+		ChatID:   qry.ChatID,
+		Kind:     kind,
+		Code:     code,
+		StopName: stopName(cb.busClient(kind), code),
+		Service:  qry.Args[1],
+		Minutes:  mins,
+		Expiry:   time.Now().Add(busAlertTTL),
+	}
+
+	if err := cb.busAlerts.add(alert); err == errTooManyAlerts {
+		text := fmt.Sprintf("🤖: You can only have %d bus alerts at a time. Use /busalerts to cancel some.", maxAlertsPerUser)
+		cb.SendMessage(EditedMessage(text, qry.ChatID, qry.MsgID))
+		return
+	} else if err != nil {
+		log.Print(err)
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while setting a bus alert")
+		return
+	}
+	text := "🤖: Okay! I'll message you when bus *" + alert.Service + "* is " + strconv.Itoa(mins) + " mins away from *" +
+		alert.StopName + "*.\nThe alert expires in an hour. Use /busalerts to cancel it."
+	cb.SendMessage(EditedMessage(text, qry.ChatID, qry.MsgID))
+}
+
+// BusAlerts lists the user's active bus alerts
+func (cb *Cinnabot) BusAlerts(msg *message) {
+	alerts := cb.busAlerts.list(msg.From.ID)
+	if len(alerts) == 0 {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: You have no bus alerts. Tap 🔔 Alert me under bus timings to set one.")
+		return
+	}
+
+	lines := []string{"🤖: Your bus alerts:"}
+	for _, alert := range alerts {
+		lines = append(lines, alert.String())
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Cancel all", "//busalert_cancel"),
+		),
+	)
+	cb.SendMessage(NewMessageWithButton(strings.Join(lines, "\n"), keyboard, msg.Chat.ID))
+}
+
+// BusAlertCancel cancels all of the user's bus alerts
+func (cb *Cinnabot) BusAlertCancel(qry *Callback) {
+	cb.busAlerts.clear(qry.From.ID)
+	cb.SendMessage(EditedMessage("🤖: Cancelled all your bus alerts.", qry.ChatID, qry.MsgID))
+}
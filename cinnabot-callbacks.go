@@ -80,6 +80,13 @@ func EditedMessageWithButton(text string, keyboard tgbotapi.InlineKeyboardMarkup
 	return msg
 }
 
+// EditedMessage creates an edited text message with any buttons removed
+func EditedMessage(text string, chatID int64, msgID int) tgbotapi.EditMessageTextConfig {
+	msg := tgbotapi.NewEditMessageText(chatID, msgID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	return msg
+}
+
 // InitCinnabot initializes an instance of Cinnabot.
 // func InitCinnabotPatch(configJSON []byte, lg *log.Logger) *Cinnabot {
 // 	if lg == nil {
@@ -14,12 +14,15 @@ import (
 
 	cache "github.com/patrickmn/go-cache"
 	"github.com/usdevs/cinnabot/model"
+	"github.com/usdevs/cinnabot/transit"
+	"github.com/usdevs/cinnabot/weather"
 	tgbotapi "gopkg.in/telegram-bot-api.v4"
 )
 
 type bot interface {
 	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
 	GetUpdatesChan(config tgbotapi.UpdateConfig) (tgbotapi.UpdatesChannel, error)
+	GetFileDirectURL(fileID string) (string, error)
 }
 
 // Cinnabot is main struct that processes user requests.
@@ -33,6 +36,17 @@ type Cinnabot struct {
 	db      model.DataGroup
 	cache   *cache.Cache
 	allTags []string
+
+	nusBus      *transit.Client
+	publicBus   *transit.Client
+	busAlerts   *busAlerts
+	nusRoutes   []busRoute
+	weather     *weather.Client
+	rainAlerts  *rainAlerts
+	airAlerts   *airAlerts
+	maps        *mapRegistry
+	venues      []venue
+	spacesWatch *spacesWatch
 }
 
 // Configuration struct for setting up Cinnabot
@@ -40,8 +54,16 @@ type config struct {
 	Name           string `json:"name"`
 	TelegramAPIKey string `json:"telegram_api_key"`
 	Admins         []int  `json:"admins"`
+	NUSBusURL      string `json:"nus_bus_url"`     // optional, defaults to transit.DefaultNUSURL
+	LTAAccountKey  string `json:"lta_account_key"` // LTA DataMall API key for public bus timings
+	WeatherAPIKey  string `json:"weather_api_key"` // optional data.gov.sg API key
+
+	NearbyStopRadius float64 `json:"nearby_stop_radius"` // metres, stops further than this from a location are not shown
 }
 
+// defaultNearbyStopRadius is used if the config does not set nearby_stop_radius
+const defaultNearbyStopRadius = 800
+
 // Wrapper struct for a message
 type message struct {
 	Cmd  string
@@ -88,6 +110,14 @@ func InitCinnabot(configJSON []byte, lg *log.Logger) *Cinnabot {
 		log.Fatalf("config.json exists, but doesn't contain any admins.")
 	}
 
+	if cfg.NearbyStopRadius <= 0 {
+		cfg.NearbyStopRadius = defaultNearbyStopRadius
+	}
+
+	if cfg.LTAAccountKey == "" {
+		lg.Printf("config.json doesn't contain an LTA DataMall account key, public bus timings will be unavailable.")
+	}
+
 	bot, err := tgbotapi.NewBotAPI(cfg.TelegramAPIKey)
 	if err != nil {
 		log.Fatalf("error creating new bot, dude %s", err)
@@ -98,8 +128,36 @@ func InitCinnabot(configJSON []byte, lg *log.Logger) *Cinnabot {
 	cb.hmap = make(map[string]CallbackFunc)
 	cb.db = model.InitializeDB()
 	cb.cache = cache.New(1*time.Minute, 2*time.Minute)
+	cb.nusBus = transit.NewClient(transit.NUS{URL: cfg.NUSBusURL, StopsFile: "nusstops.json"}, 20*time.Second, 5*time.Second)
+	cb.publicBus = transit.NewClient(transit.LTA{AccountKey: cfg.LTAAccountKey, StopsFile: "publicstops.json"}, 30*time.Second, 5*time.Second)
+	// Load the bus stops now, rather than on the first request
+	for _, client := range []*transit.Client{cb.nusBus, cb.publicBus} {
+		if _, err := client.Stops(); err != nil {
+			lg.Printf("cannot load bus stops: %s", err)
+		}
+	}
+	cb.busAlerts = newBusAlerts()
+	cb.weather = weather.NewClient(weather.DefaultBaseURL, cfg.WeatherAPIKey, 5*time.Minute, 10*time.Second)
+	cb.rainAlerts = newRainAlerts()
+	cb.airAlerts = newAirAlerts()
+	cb.spacesWatch = newSpacesWatch()
+	if cb.nusRoutes, err = readRoutes("nusroutes.json"); err != nil {
+		lg.Printf("cannot read NUS shuttle routes, /route will be unavailable: %s", err)
+	}
+	if cb.maps, err = readMaps("maps.json"); err != nil {
+		lg.Printf("cannot read maps, /map will be unavailable: %s", err)
+	}
+	if err := cb.loadResources("resources.json"); err != nil {
+		lg.Printf("cannot load resources: %s", err)
+	}
+	if err := cb.loadInterestGroups("resources.json"); err != nil {
+		lg.Printf("cannot load interest groups: %s", err)
+	}
+	if cb.venues, err = readVenues("venues.json"); err != nil {
+		lg.Printf("cannot read venues, /where will be unavailable: %s", err)
+	}
 	//tag alternates with tag description
-	cb.allTags = []string{"everything", "EVERY tag!! Only for the daring", "events", "EVENTS of cinnamon college", "food", "Free/not free FOOD updates of all kind for the hungry", "weather", "Weather updates. Im not sure why you would want it actually.", "warm", "If you want some nice warm things occasionally"}
+	cb.allTags = []string{"everything", "EVERY tag!! Only for the daring", "events", "EVENTS of cinnamon college", "food", "Free/not free FOOD updates of all kind for the hungry", "weather", "Rain alerts for your area, set with /rainalerts", "warm", "If you want some nice warm things occasionally"}
 
 	return cb
 }
@@ -190,13 +248,21 @@ func (cb *Cinnabot) CheckArgCmdPair(cmd string, args []string) bool {
 	checkMap["/diningfeedback"] = []string{"anything"}
 	checkMap["/residentialfeedback"] = []string{"anything"}
 	checkMap["/ohsfeedback"] = []string{"anything"}
-
-	checkMap["/resources"] = []string{"telegram", "links", "interest", "everything", ""}
-
-	checkMap["/map"] = []string{"utown", "science", "arts", "law", "yih/engin", "cenlib", "biz", "yih", "kr-mrt", "mpsh", "comp", "", "nus", "sde"}
-	checkMap["/publicbus"] = []string{"cinnamon", ""}
-	checkMap["/nusbus"] = []string{"utown", "science", "arts", "law", "yih/engin", "cenlib", "biz", "yih", "kr-mrt", "mpsh", "comp", ""}
-	checkMap["/weather"] = []string{"cinnamon", ""}
+	checkMap["/laundryphoto"] = []string{"anything"}
+
+	checkMap["/resources"] = []string{"anything"}     // a tag, or something to search for
+	checkMap["/editresources"] = []string{"anything"} // a file to import
+	checkMap["/groups"] = []string{"anything"}        // something to search for
+
+	checkMap["/map"] = cb.maps.args()
+	checkMap["/where"] = []string{"anything"}
+	checkMap["/publicbus"] = []string{"anything"} // a location, "cinnamon", or a stop to search for
+	checkMap["/nusbus"] = []string{"utown", "science", "arts", "law", "yih/engin", "cenlib", "biz", "yih", "kr-mrt", "mpsh", "comp", "", placePrefix}
+	checkMap["/route"] = []string{"anything"}
+	checkMap["/weather"] = []string{"cinnamon", "", "now", "today", "4day", placePrefix}
+	checkMap["/rainalerts"] = []string{"anything"} // a location or an area
+	checkMap["/places"] = []string{"", "add", "delete"}
+	checkMap["/air"] = []string{"cinnamon", "", placePrefix}
 
 	arr := checkMap[cmd]
 	for i := 0; i < len(arr); i++ {
@@ -212,24 +278,51 @@ func (cb *Cinnabot) CheckArgCmdPair(cmd string, args []string) bool {
 	return false
 }
 
+// isAdmin checks if the user with the given id is listed as an admin in the config.
+func (cb *Cinnabot) isAdmin(id int) bool {
+	for _, admin := range cb.keys.Admins {
+		if admin == id {
+			return true
+		}
+	}
+	return false
+}
+
 // GoSafely is a utility wrapper to recover and log panics in goroutines.
 // If we use naked goroutines, a panic in any one of them crashes
 // the whole program. Using GoSafely prevents this.
 func (cb *Cinnabot) GoSafely(fn func()) {
 	go func() {
-		defer func() {
-			if err := recover(); err != nil {
-				stack := make([]byte, 1024*8)
-				stack = stack[:runtime.Stack(stack, false)]
-
-				cb.log.Printf("PANIC: %s\n%s", err, stack)
-			}
-		}()
-
+		defer cb.logPanic()
 		fn()
 	}()
 }
 
+// Every runs job in the background once every interval. A panic in one run of the job
+// is logged, and does not stop the job from running again.
+func (cb *Cinnabot) Every(interval time.Duration, job func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			func() {
+				defer cb.logPanic()
+				job()
+			}()
+		}
+	}()
+}
+
+// logPanic recovers from and logs a panic. It must be deferred.
+func (cb *Cinnabot) logPanic() {
+	if err := recover(); err != nil {
+		stack := make([]byte, 1024*8)
+		stack = stack[:runtime.Stack(stack, false)]
+
+		cb.log.Printf("PANIC: %s\n%s", err, stack)
+	}
+}
+
 // Helper to parse incoming messages and return Cinnabot messages
 func (cb *Cinnabot) parseMessage(msg *tgbotapi.Message) *message {
 	cmd := ""
@@ -263,16 +356,79 @@ func (cb *Cinnabot) parseMessage(msg *tgbotapi.Message) *message {
 	return &message{Cmd: cmd, Args: args, Message: msg}
 }
 
-// SendTextMessage sends a basic text message back to the specified user.
+// SendTextMessage sends a basic text message back to the specified user. Text which is too long
+// for one message is split into several. Errors are logged, and the first is returned.
 func (cb *Cinnabot) SendTextMessage(recipient int, text string) error {
 	msg := tgbotapi.NewMessage(int64(recipient), text)
 	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
 	msg.ParseMode = "Markdown"
-	_, err := cb.bot.Send(msg)
-	return err
+	return cb.send(msg)
 }
 
-// SendMessage sends messages which require non-default options such as reply markups.
+// SendMessage sends messages which require non-default options such as reply markups. Errors
+// are logged.
 func (cb *Cinnabot) SendMessage(chattable tgbotapi.Chattable) {
-	cb.bot.Send(chattable)
+	cb.send(chattable)
+}
+
+// send sends a message, splitting text which is too long into several messages. Edited messages
+// are edited to the first part, and the rest is sent as new messages after them.
+func (cb *Cinnabot) send(chattable tgbotapi.Chattable) error {
+	switch msg := chattable.(type) {
+	case tgbotapi.MessageConfig:
+		if telegramLength(msg.Text) > maxMessageLength {
+			return cb.sendParts(msg, splitMessage(msg.Text, maxMessageLength, msg.ParseMode == tgbotapi.ModeMarkdown))
+		}
+	case tgbotapi.EditMessageTextConfig:
+		if telegramLength(msg.Text) > maxMessageLength {
+			return cb.sendEditParts(msg)
+		}
+	}
+	return cb.logSend(chattable)
+}
+
+// sendParts sends each part of the text of msg as a message. Only the last part has the reply
+// markup, so that buttons come after all the text.
+func (cb *Cinnabot) sendParts(msg tgbotapi.MessageConfig, parts []string) error {
+	var firstErr error
+	for i, text := range parts {
+		part := msg
+		part.Text = text
+		if i < len(parts)-1 {
+			part.ReplyMarkup = nil
+		}
+		if err := cb.logSend(part); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// sendEditParts edits a message to the first part of text which is too long for one message, and
+// sends the rest after it. The edited message keeps its buttons, so that they edit it again.
+func (cb *Cinnabot) sendEditParts(edit tgbotapi.EditMessageTextConfig) error {
+	parts := splitMessage(edit.Text, maxMessageLength, edit.ParseMode == tgbotapi.ModeMarkdown)
+	edit.Text = parts[0]
+	err := cb.logSend(edit)
+	if edit.ChatID == 0 || len(parts) == 1 {
+		// inline messages have no chat to send the rest to
+		return err
+	}
+
+	rest := tgbotapi.NewMessage(edit.ChatID, "")
+	rest.ParseMode = edit.ParseMode
+	rest.DisableWebPagePreview = edit.DisableWebPagePreview
+	if restErr := cb.sendParts(rest, parts[1:]); err == nil {
+		err = restErr
+	}
+	return err
+}
+
+// logSend sends a message, and logs why it could not be sent
+func (cb *Cinnabot) logSend(chattable tgbotapi.Chattable) error {
+	_, err := cb.bot.Send(chattable)
+	if err != nil {
+		cb.log.Printf("cannot send %T: %s", chattable, err)
+	}
+	return err
 }
@@ -0,0 +1,108 @@
+// Command busstops regenerates the bus stop datasets read by Cinnabot (publicstops.json and
+// nusstops.json). The new stops are validated and compared against the existing file, which is
+// only overwritten if -write is given.
+//
+// Public bus stops are fetched from LTA DataMall, or read from a local dump of the BusStops API:
+//
+//	go run ./cmd/busstops -key <LTA account key>
+//	go run ./cmd/busstops -dump busstops.json -write
+//
+// NUS shuttle bus stops are read from a dump of the NUS shuttle BusStops API:
+//
+//	go run ./cmd/busstops -kind nus -dump nusbusstops.json -write
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/usdevs/cinnabot/transit"
+)
+
+func main() {
+	kind := flag.String("kind", "public", "which stops to regenerate, public or nus")
+	dump := flag.String("dump", "", "read stops from a local dump of the API instead of fetching them")
+	key := flag.String("key", os.Getenv("LTA_ACCOUNT_KEY"), "LTA DataMall account key, defaults to $LTA_ACCOUNT_KEY")
+	out := flag.String("out", "", "stops file to compare against and write, defaults to main/<kind>stops.json")
+	write := flag.Bool("write", false, "overwrite the stops file if the new stops are valid")
+	flag.Parse()
+
+	if *out == "" {
+		*out = "main/" + *kind + "stops.json"
+	}
+
+	var stops []transit.Stop
+	var code *regexp.Regexp
+	var err error
+	switch {
+	case *kind == "public" && *dump != "":
+		stops, err = readDump(*dump, transit.ParseLTAStops)
+		code = transit.PublicStopCode
+	case *kind == "public":
+		if *key == "" {
+			log.Fatal("an LTA DataMall account key is needed to fetch public bus stops, set -key or use -dump")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		stops, err = transit.FetchLTAStops(ctx, "", *key)
+		code = transit.PublicStopCode
+	case *kind == "nus" && *dump != "":
+		stops, err = readDump(*dump, transit.ParseNUSStops)
+	case *kind == "nus":
+		log.Fatal("NUS shuttle bus stops can only be read from a dump, set -dump")
+	default:
+		log.Fatalf("unknown kind of stops %q, should be public or nus", *kind)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(stops) == 0 {
+		log.Fatal("no stops found")
+	}
+
+	if errs := transit.ValidateStops(stops, code); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		log.Fatalf("%d invalid stops, not writing %s", len(errs), *out)
+	}
+
+	old, err := transit.ReadStops(*out)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	diff := transit.DiffStops(old, stops)
+	fmt.Print(diff)
+
+	if !*write {
+		fmt.Printf("run with -write to update %s\n", *out)
+		return
+	}
+	if diff.Empty() {
+		fmt.Printf("%s is up to date\n", *out)
+		return
+	}
+	if err := transit.WriteStops(*out, stops); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote %d stops to %s\n", len(stops), *out)
+}
+
+// readDump reads stops from a local dump of an API response
+func readDump(path string, parse func([]byte) ([]transit.Stop, error)) ([]transit.Stop, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stops, err := parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return stops, nil
+}
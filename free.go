@@ -0,0 +1,186 @@
+package cinnabot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/usdevs/cinnabot/utils"
+)
+
+// Operating hours of the USC spaces, as hours after midnight. Only gaps in these hours are free.
+const (
+	spacesOpenHour  = 8
+	spacesCloseHour = 23
+)
+
+// minFreeSlot is the shortest gap between bookings worth showing
+const minFreeSlot = 15 * time.Minute
+
+// slot is a period of time when a venue is free
+type slot struct {
+	Start time.Time
+	End   time.Time
+}
+
+// String returns the times of the slot, eg. "10AM to 01:30PM"
+func (s slot) String() string {
+	return fmt.Sprintf("%s to %s", FormatTime(s.Start), FormatTime(s.End))
+}
+
+// operatingHours returns when the spaces open and close on the day of date (SG time)
+func operatingHours(date time.Time) (time.Time, time.Time) {
+	date = date.In(utils.SgLocation())
+	y, m, d := date.Date()
+	open := time.Date(y, m, d, spacesOpenHour, 0, 0, 0, date.Location())
+	close := time.Date(y, m, d, spacesCloseHour, 0, 0, 0, date.Location())
+	return open, close
+}
+
+// freeSlots returns the gaps between the events in space from start to end, ignoring gaps shorter
+// than minFreeSlot
+func freeSlots(space Space, start, end time.Time) []slot {
+	booked := space.filter(eventBetween(start, end))
+	sort.Sort(byStartDate(booked))
+
+	var slots []slot
+	from := start
+	for _, event := range booked {
+		if event.Start.Sub(from) >= minFreeSlot {
+			slots = append(slots, slot{from, event.Start})
+		}
+		if event.End.After(from) {
+			from = event.End
+		}
+	}
+	if end.Sub(from) >= minFreeSlot {
+		slots = append(slots, slot{from, end})
+	}
+	return slots
+}
+
+// freeVenues returns the venues with no events from start to end
+func freeVenues(spaces Spaces, venues []string, start, end time.Time) []string {
+	free := make([]string, 0, len(venues))
+	for _, venue := range venues {
+		if len(spaces.space(venue).filter(eventBetween(start, end))) == 0 {
+			free = append(free, venue)
+		}
+	}
+	return free
+}
+
+// parseFreeDay parses "today", "tomorrow" or a dd/mm(/yy) date
+func parseFreeDay(arg string, now time.Time) (time.Time, error) {
+	switch arg {
+	case "today":
+		return now, nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	}
+	return ParseDDMMYYDate(arg)
+}
+
+// parseTimeRange parses a HH:MM-HH:MM time range on the day of date (SG time)
+func parseTimeRange(arg string, date time.Time) (time.Time, time.Time, error) {
+	times := strings.Split(arg, "-")
+	if len(times) != 2 {
+		return time.Time{}, time.Time{}, errors.New("time range must be HH:MM-HH:MM")
+	}
+	date = date.In(utils.SgLocation())
+	y, m, d := date.Date()
+	var bounds [2]time.Time
+	for i, s := range times {
+		t, err := time.Parse("15:04", strings.TrimSpace(s))
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		bounds[i] = time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, date.Location())
+	}
+	if !bounds[0].Before(bounds[1]) {
+		return time.Time{}, time.Time{}, errors.New("time range must end after it starts")
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// freeSlotsMessage returns when a venue is free on a day. Time which has already passed today is
+// left out.
+func freeSlotsMessage(spaces Spaces, venue string, date, now time.Time) string {
+	open, close := operatingHours(date)
+	if now.After(open) {
+		open = now
+	}
+	header := fmt.Sprintf("%s is free on %s:\n\n", venue, FormatDate(date.In(utils.SgLocation())))
+	if !open.Before(close) {
+		return header + "[Closed for the day]"
+	}
+
+	slots := freeSlots(spaces.space(venue), open, close)
+	if len(slots) == 0 {
+		return header + "[Fully booked]"
+	}
+	lines := make([]string, len(slots))
+	for i, s := range slots {
+		lines[i] = s.String()
+	}
+	return header + strings.Join(lines, "\n")
+}
+
+// freeVenuesMessage returns the venues which are free from start to end
+func freeVenuesMessage(spaces Spaces, start, end time.Time) string {
+	header := fmt.Sprintf("Venues free from %s to %s, %s:\n\n", FormatTime(start), FormatTime(end), FormatDate(start))
+	venues := freeVenues(spaces, spaces.venueNames(), start, end)
+	if len(venues) == 0 {
+		return header + "[No free venues]"
+	}
+	return header + strings.Join(venues, "\n")
+}
+
+// freeHelp explains how to use /free
+const freeHelp = "To use the '/free' command, type one of the following:\n'/free <venue>' : to view when a venue is free today, eg. '/free chill room'\n'/free <venue> dd/mm(/yy)' : to view when a venue is free on a specific day\n'/free dd/mm(/yy) HH:MM-HH:MM' : to view the venues free at a specific time, eg. '/free today 14:00-16:00'"
+
+// Free shows when venues are free, from the gaps between their bookings in operating hours.
+//
+//	"/free <venue> [date]" displays the free slots at the venue today, or on the date.
+//	"/free <date> <HH:MM-HH:MM>" displays the venues with no bookings at that time.
+//	"/free help" informs the user of available commands.
+func (cb *Cinnabot) Free(msg *message) {
+	now := time.Now().In(utils.SgLocation())
+	if len(msg.Args) == 0 || msg.Args[0] == "help" {
+		cb.SendTextMessage(msg.From.ID, freeHelp)
+		return
+	}
+
+	if len(msg.Args) == 2 {
+		if date, err := parseFreeDay(msg.Args[0], now); err == nil {
+			start, end, err := parseTimeRange(msg.Args[1], date)
+			if err != nil {
+				cb.SendTextMessage(msg.From.ID, "Cinnabot was unable to understand that time range.\n\n"+freeHelp)
+				return
+			}
+			spaces := getSpacesAfter(now.AddDate(0, 0, -venueLookbackDays))
+			cb.SendTextMessage(msg.From.ID, freeVenuesMessage(spaces, start, end))
+			return
+		}
+	}
+
+	query, date := strings.Join(msg.Args, " "), now
+	if last := len(msg.Args) - 1; last > 0 {
+		if day, err := parseFreeDay(msg.Args[last], now); err == nil {
+			query, date = strings.Join(msg.Args[:last], " "), day
+		}
+	}
+	after := now.AddDate(0, 0, -venueLookbackDays)
+	if date.Before(after) {
+		after = date
+	}
+	spaces := getSpacesAfter(after)
+	venue, ok := matchVenue(spaces.venueNames(), query)
+	if !ok {
+		cb.SendTextMessage(msg.From.ID, "Cinnabot couldn't find that venue. Type '/spaces venues' to see them all.")
+		return
+	}
+	cb.SendTextMessage(msg.From.ID, freeSlotsMessage(spaces, venue, date, now))
+}
@@ -0,0 +1,69 @@
+package cinnabot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/usdevs/cinnabot/utils"
+)
+
+func TestFreeSlots(t *testing.T) {
+	day := time.Date(2018, 11, 19, 0, 0, 0, 0, utils.SgLocation())
+	at := func(hour, min int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute)
+	}
+	space := Space{
+		{Name: "Late", Venue: "Chill Room", Start: at(14, 0), End: at(16, 0)},
+		{Name: "Early", Venue: "Chill Room", Start: at(7, 0), End: at(9, 0)},
+		{Name: "Overlapping", Venue: "Chill Room", Start: at(15, 0), End: at(17, 0)},
+		{Name: "Short gap", Venue: "Chill Room", Start: at(17, 10), End: at(18, 0)},
+	}
+	open, close := operatingHours(day)
+	slots := freeSlots(space, open, close)
+	want := []slot{{at(9, 0), at(14, 0)}, {at(18, 0), at(23, 0)}}
+	if len(slots) != len(want) {
+		t.Fatalf("freeSlots() = %v, want %v", slots, want)
+	}
+	for i := range want {
+		if !slots[i].Start.Equal(want[i].Start) || !slots[i].End.Equal(want[i].End) {
+			t.Errorf("slot %d = %v, want %v", i, slots[i], want[i])
+		}
+	}
+}
+
+func TestFreeVenuesMessage(t *testing.T) {
+	day := time.Date(2018, 11, 19, 0, 0, 0, 0, utils.SgLocation())
+	spaces := Spaces{
+		Space{{Name: "Band practice", Venue: "Chill Room", Start: day.Add(14 * time.Hour), End: day.Add(16 * time.Hour)}},
+		Space{{Name: "Talk", Venue: "Cinnamon Lounge", Start: day.Add(10 * time.Hour), End: day.Add(12 * time.Hour)}},
+	}
+	start, end, err := parseTimeRange("15:00-17:00", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := freeVenuesMessage(spaces, start, end)
+	if strings.Contains(text, "Chill Room") || !strings.Contains(text, "Cinnamon Lounge") {
+		t.Errorf("unexpected free venues:\n%s", text)
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	day := time.Date(2018, 11, 19, 0, 0, 0, 0, utils.SgLocation())
+	for _, arg := range []string{"15:00", "17:00-15:00", "3pm-5pm", "15:00-25:00"} {
+		if _, _, err := parseTimeRange(arg, day); err == nil {
+			t.Errorf("parseTimeRange(%q) did not fail", arg)
+		}
+	}
+}
+
+func TestFreeSlotsMessage(t *testing.T) {
+	day := time.Date(2018, 11, 19, 0, 0, 0, 0, utils.SgLocation())
+	spaces := Spaces{Space{{Name: "All day", Venue: "Chill Room", Start: day, End: day.AddDate(0, 0, 1)}}}
+	if text := freeSlotsMessage(spaces, "Chill Room", day, day); !strings.HasSuffix(text, "[Fully booked]") {
+		t.Errorf("unexpected message:\n%s", text)
+	}
+	if text := freeSlotsMessage(spaces, "Chill Room", day, day.Add(23*time.Hour+30*time.Minute)); !strings.HasSuffix(text, "[Closed for the day]") {
+		t.Errorf("unexpected message:\n%s", text)
+	}
+}
@@ -0,0 +1,406 @@
+package cinnabot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/usdevs/cinnabot/model"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// interestGroupsJSON is the key of the interest groups in resources.json
+const interestGroupsJSON = "interest_groups"
+
+const (
+	groupsPerPage   = 8
+	maxGroupResults = 6
+)
+
+// handlePattern matches the Telegram handles in the contacts of a group, whose underscores may be
+// escaped for Markdown, eg. @some\_name
+var handlePattern = regexp.MustCompile(`@((?:[A-Za-z0-9]|\\?_)+)`)
+
+// contactHandles returns the Telegram usernames in the contacts of a group, without the @
+func contactHandles(contacts string) []string {
+	handles := make([]string, 0)
+	for _, match := range handlePattern.FindAllStringSubmatch(contacts, -1) {
+		handles = append(handles, strings.Replace(match[1], `\`, "", -1))
+	}
+	return handles
+}
+
+// escapeMarkdown escapes the characters which start formatting in Telegram's Markdown, for text
+// which users choose, eg. their names
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer("_", `\_`, "*", `\*`, "`", "\\`", "[", `\[`).Replace(text)
+}
+
+// contactsMarkdown escapes the contacts of a group for Markdown. The underscores in contacts loaded
+// from resources.json are escaped already, so they are not escaped twice.
+func contactsMarkdown(contacts string) string {
+	return escapeMarkdown(strings.Replace(contacts, `\_`, "_", -1))
+}
+
+// boldMarkdown makes text bold in Telegram's Markdown. Escapes are not allowed inside entities,
+// so the bold is closed around each asterisk in text, eg. *2*\**2=4* for 2*2=4.
+func boldMarkdown(text string) string {
+	parts := strings.Split(text, "*")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = "*" + part + "*"
+		}
+	}
+	return strings.Join(parts, `\*`)
+}
+
+// parseInterestGroups parses the interest groups in resources.json, which maps their names to
+// their contacts
+func parseInterestGroups(data []byte) ([]model.InterestGroup, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	var contacts map[string]string
+	if groups, ok := raw[interestGroupsJSON]; ok {
+		if err := json.Unmarshal(groups, &contacts); err != nil {
+			return nil, fmt.Errorf("%s: %w", interestGroupsJSON, err)
+		}
+	}
+	groups := make([]model.InterestGroup, 0, len(contacts))
+	for name, c := range contacts {
+		groups = append(groups, model.InterestGroup{Name: name, Contacts: c})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// loadInterestGroups adds the interest groups in a file in the format of resources.json, if there
+// are none yet. After that admins edit them with /editgroups.
+func (cb *Cinnabot) loadInterestGroups(path string) error {
+	if len(cb.db.InterestGroups()) > 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	groups, err := parseInterestGroups(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, g := range groups {
+		if err := cb.db.AddInterestGroup(0, g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchGroups returns the interest groups which best match query, best match first
+func searchGroups(groups []model.InterestGroup, query string) []model.InterestGroup {
+	fields := make([][]string, len(groups))
+	for i, g := range groups {
+		fields[i] = []string{g.Name, g.Description}
+	}
+	results := make([]model.InterestGroup, 0)
+	for _, i := range bestMatches(query, fields, maxGroupResults) {
+		results = append(results, groups[i])
+	}
+	return results
+}
+
+// formatGroupLines lists the contacts of interest groups
+func formatGroupLines(groups []model.InterestGroup) string {
+	lines := make([]string, len(groups))
+	for i, g := range groups {
+		lines[i] = escapeMarkdown(g.Name) + " : " + contactsMarkdown(g.Contacts)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// groupButtons returns a button to open each group
+func groupButtons(groups []model.InterestGroup) [][]tgbotapi.InlineKeyboardButton {
+	rows := make([][]tgbotapi.InlineKeyboardButton, len(groups))
+	for i, g := range groups {
+		rows[i] = tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(g.Name, fmt.Sprintf("//group %d", g.ID)))
+	}
+	return rows
+}
+
+// groupsPage lists a page of the interest groups, with a button to open each and Prev and Next
+// buttons. The page is clamped to the pages there are.
+func groupsPage(groups []model.InterestGroup, page int) (string, tgbotapi.InlineKeyboardMarkup) {
+	pages := (len(groups) + groupsPerPage - 1) / groupsPerPage
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	end := (page + 1) * groupsPerPage
+	if end > len(groups) {
+		end = len(groups)
+	}
+
+	text := "🤖: Here are the interest groups in Cinnamon! Tap one to find out more and ask to join."
+	if pages > 1 {
+		text += fmt.Sprintf(" (page %d of %d)", page+1, pages)
+	}
+	rows := groupButtons(groups[page*groupsPerPage : end])
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("⬅️ Prev", fmt.Sprintf("//groups_page %d", page-1)))
+	}
+	if page < pages-1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Next ➡️", fmt.Sprintf("//groups_page %d", page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// groupCard describes an interest group, with buttons to ask to join and open its chat
+func groupCard(g model.InterestGroup) (string, tgbotapi.InlineKeyboardMarkup) {
+	text := boldMarkdown(g.Name) + "\n"
+	if g.Description != "" {
+		text += escapeMarkdown(g.Description) + "\n"
+	}
+	text += "\n"
+	if g.Schedule != "" {
+		text += "🗓 " + escapeMarkdown(g.Schedule) + "\n"
+	}
+	if g.Contacts != "" {
+		text += "👥 Contacts: " + contactsMarkdown(g.Contacts) + "\n"
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🙋 Request to join", fmt.Sprintf("//group_join %d", g.ID))),
+	}
+	if g.Link != "" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL("💬 Group chat", g.Link)))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ All groups", "//groups_page 0")))
+	return strings.TrimSuffix(text, "\n"), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// sendGroups sends the first page of interest groups
+func (cb *Cinnabot) sendGroups(chatID int64) {
+	groups := cb.db.InterestGroups()
+	if len(groups) == 0 {
+		cb.SendTextMessage(int(chatID), "🤖: There are no interest groups yet.")
+		return
+	}
+	text, keyboard := groupsPage(groups, 0)
+	cb.SendMessage(NewMessageWithButton(text, keyboard, chatID))
+}
+
+// Groups lists the interest groups, or searches them with /groups <search>
+func (cb *Cinnabot) Groups(msg *message) {
+	query := strings.TrimSpace(msg.GetArgString())
+	if query == "" {
+		cb.sendGroups(msg.Chat.ID)
+		return
+	}
+
+	groups := searchGroups(cb.db.InterestGroups(), query)
+	switch len(groups) {
+	case 0:
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: I couldn't find a group like that. Send /groups to see them all.")
+	case 1:
+		text, keyboard := groupCard(groups[0])
+		cb.SendMessage(NewMessageWithButton(text, keyboard, msg.Chat.ID))
+	default:
+		cb.SendMessage(NewMessageWithButton("🤖: Which one did you mean?", tgbotapi.NewInlineKeyboardMarkup(groupButtons(groups)...), msg.Chat.ID))
+	}
+}
+
+// GroupsPage shows another page of interest groups
+func (cb *Cinnabot) GroupsPage(qry *Callback) {
+	page, err := strconv.Atoi(qry.GetArgString())
+	if err != nil {
+		return
+	}
+	groups := cb.db.InterestGroups()
+	if len(groups) == 0 {
+		cb.SendMessage(EditedMessage("🤖: There are no interest groups yet.", qry.ChatID, qry.MsgID))
+		return
+	}
+	text, keyboard := groupsPage(groups, page)
+	cb.SendMessage(EditedMessageWithButton(text, keyboard, qry.ChatID, qry.MsgID))
+}
+
+// callbackGroup returns the interest group whose id is the argument of a callback
+func (cb *Cinnabot) callbackGroup(qry *Callback) (model.InterestGroup, bool) {
+	id, err := strconv.ParseUint(qry.GetArgString(), 10, 32)
+	if err != nil {
+		return model.InterestGroup{}, false
+	}
+	group, err := cb.db.InterestGroup(uint(id))
+	if err != nil {
+		cb.SendMessage(EditedMessage("🤖: That group doesn't exist any more. Send /groups to see them all.", qry.ChatID, qry.MsgID))
+		return model.InterestGroup{}, false
+	}
+	return group, true
+}
+
+// GroupShow shows an interest group picked from a list
+func (cb *Cinnabot) GroupShow(qry *Callback) {
+	group, ok := cb.callbackGroup(qry)
+	if !ok {
+		return
+	}
+	text, keyboard := groupCard(group)
+	cb.SendMessage(EditedMessageWithButton(text, keyboard, qry.ChatID, qry.MsgID))
+}
+
+// joinRequestText is sent to the contacts of a group when someone asks to join it
+func joinRequestText(from *tgbotapi.User, group model.InterestGroup) string {
+	who := fmt.Sprintf("[%s](tg://user?id=%d)", escapeMarkdown(from.FirstName), from.ID)
+	if from.UserName != "" {
+		who += " (@" + escapeMarkdown(from.UserName) + ")"
+	}
+	return fmt.Sprintf("🙋 %s would like to join %s! Drop them a message to welcome them in.", who, boldMarkdown(group.Name))
+}
+
+// sendJoinRequest tells the contacts of the group that the user would like to join, or the admins
+// if none of the contacts could be told. It returns how many people were told.
+func (cb *Cinnabot) sendJoinRequest(from *tgbotapi.User, group model.InterestGroup) int {
+	text := joinRequestText(from, group)
+	sent := 0
+	for _, contact := range cb.db.UsersByUserName(contactHandles(group.Contacts)) {
+		if err := cb.SendTextMessage(contact.UserID, text); err == nil {
+			sent++
+		}
+	}
+	if sent > 0 {
+		return sent
+	}
+	text += "\n\nNone of its contacts (" + contactsMarkdown(group.Contacts) + ") have started Cinnabot, please pass this on."
+	for _, admin := range cb.keys.Admins {
+		if err := cb.SendTextMessage(admin, text); err == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// GroupJoin tells the contacts of an interest group that the user would like to join. If none of
+// the contacts have started Cinnabot, the admins are told instead so that they can pass it on.
+func (cb *Cinnabot) GroupJoin(qry *Callback) {
+	group, ok := cb.callbackGroup(qry)
+	if !ok {
+		return
+	}
+	isNew, err := cb.db.AddJoinRequest(group.ID, qry.From.ID)
+	if err != nil {
+		cb.log.Printf("cannot save join request: %s", err)
+		cb.SendTextMessage(int(qry.ChatID), "🤖: Sorry, I couldn't send your request. Please try again later.")
+		return
+	}
+	if !isNew {
+		cb.SendTextMessage(int(qry.ChatID), "🤖: You've already asked to join "+escapeMarkdown(group.Name)+". Its contacts will be in touch!")
+		return
+	}
+
+	if cb.sendJoinRequest(qry.From, group) == 0 {
+		// nobody was told, so let the user ask again
+		if err := cb.db.DeleteJoinRequest(group.ID, qry.From.ID); err != nil {
+			cb.log.Printf("cannot delete join request: %s", err)
+		}
+		cb.SendTextMessage(int(qry.ChatID), "🤖: Sorry, I couldn't send your request. Please try again later.")
+		return
+	}
+
+	reply := "🤖: I've let the " + escapeMarkdown(group.Name) + " contacts know, they'll message you soon!"
+	if group.Link != "" {
+		reply += " In the meantime, you can join the group chat: " + escapeMarkdown(group.Link)
+	}
+	cb.SendTextMessage(int(qry.ChatID), reply)
+}
+
+// editGroupsUsage is sent without Markdown, so that the example is shown as typed
+const editGroupsUsage = "🤖: Edit the interest groups shown by /groups:\n" +
+	"/editgroups add <name>\n" +
+	"/editgroups remove <name>\n" +
+	"/editgroups <field> <name> = <value>\n\n" +
+	"Fields are description, contacts, schedule and link (an invite link to the group chat), eg.\n" +
+	"/editgroups schedule Ultimate Frisbee = Tuesdays 8pm at the field\n\n" +
+	"Contacts are Telegram handles, and are told when someone asks to join if they have started Cinnabot. " +
+	"Changes show up in /editresources log."
+
+// EditGroups lets admins add, edit and remove interest groups
+func (cb *Cinnabot) EditGroups(msg *message) {
+	if !cb.isAdmin(msg.From.ID) {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Only admins can edit interest groups.")
+		return
+	}
+	if len(msg.Args) < 2 {
+		cb.SendMessage(tgbotapi.NewMessage(msg.Chat.ID, editGroupsUsage))
+		return
+	}
+
+	action := strings.ToLower(msg.Args[0])
+	rest := strings.Join(msg.Args[1:], " ")
+	var err error
+	switch action {
+	case "add":
+		err = cb.db.AddInterestGroup(msg.From.ID, model.InterestGroup{Name: rest})
+	case "remove":
+		err = cb.db.DeleteInterestGroup(msg.From.ID, rest)
+	default:
+		if _, ok := (model.InterestGroup{}).Fields()[action]; !ok {
+			cb.SendMessage(tgbotapi.NewMessage(msg.Chat.ID, editGroupsUsage))
+			return
+		}
+		i := strings.Index(rest, "=")
+		if i < 0 || strings.TrimSpace(rest[:i]) == "" {
+			cb.SendTextMessage(int(msg.Chat.ID), "🤖: I couldn't read that (missing = between the name and value). Send /editgroups to see how.")
+			return
+		}
+		value := strings.TrimSpace(rest[i+1:])
+		// Telegram rejects messages with buttons which aren't links
+		if action == "link" && value != "" && !strings.HasPrefix(value, "https://") {
+			cb.SendTextMessage(int(msg.Chat.ID), "🤖: The link should be an invite link starting with https://, eg. https://t.me/joinchat/...")
+			return
+		}
+		// An empty value clears the field
+		err = cb.db.EditInterestGroup(msg.From.ID, strings.TrimSpace(rest[:i]), action, value)
+	}
+
+	switch {
+	case err == nil && action == "remove":
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Removed.")
+	case err == nil:
+		group, _ := findGroupByName(cb.db.InterestGroups(), strings.TrimSpace(strings.SplitN(rest, "=", 2)[0]))
+		text, _ := groupCard(group)
+		if cb.SendTextMessage(int(msg.Chat.ID), "🤖: Saved! It now shows as:\n\n"+text) != nil {
+			cb.SendMessage(tgbotapi.NewMessage(msg.Chat.ID, "🤖: Saved, but it isn't valid Markdown, so the group may not be shown. "+
+				"Check for unescaped _ or * and edit it again."))
+		}
+	case errors.Is(err, model.ErrInterestGroupExists):
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: There's already a group with that name.")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: There's no group with that name. Check /groups for the exact name.")
+	default:
+		cb.log.Printf("cannot edit interest groups: %s", err)
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Sorry, I couldn't save the change. Please try again later.")
+	}
+}
+
+// findGroupByName returns the group with the given name, ignoring case
+func findGroupByName(groups []model.InterestGroup, name string) (model.InterestGroup, bool) {
+	for _, g := range groups {
+		if strings.EqualFold(g.Name, name) {
+			return g, true
+		}
+	}
+	return model.InterestGroup{}, false
+}
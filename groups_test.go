@@ -0,0 +1,134 @@
+package cinnabot
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/usdevs/cinnabot/model"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+func TestInterestGroupsData(t *testing.T) {
+	data, err := ioutil.ReadFile("main/resources.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := parseInterestGroups(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) == 0 {
+		t.Fatal("no interest groups")
+	}
+	for _, g := range groups {
+		if len(contactHandles(g.Contacts)) == 0 {
+			t.Errorf("%s has no contacts in %q", g.Name, g.Contacts)
+		}
+	}
+	if results := searchGroups(groups, "frisbee"); len(results) == 0 || results[0].Name != "Ultimate Frisbee" {
+		t.Errorf("expected Ultimate Frisbee first, got %v", results)
+	}
+}
+
+func TestContactHandles(t *testing.T) {
+	tests := []struct {
+		contacts string
+		expected []string
+	}{
+		{"@andyylam", []string{"andyylam"}},
+		{"@cheongsiu, @chlobao", []string{"cheongsiu", "chlobao"}},
+		{`@ramana\_LFC`, []string{"ramana_LFC"}},
+		{"(Men's) @bioniclelee, (Women's) @wxiting", []string{"bioniclelee", "wxiting"}},
+		{"ask at the front desk", []string{}},
+	}
+	for _, test := range tests {
+		if got := contactHandles(test.contacts); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("contactHandles(%q) = %v, expected %v", test.contacts, got, test.expected)
+		}
+	}
+}
+
+func TestGroupsPage(t *testing.T) {
+	var groups []model.InterestGroup
+	for i := 0; i < groupsPerPage+1; i++ {
+		g := model.InterestGroup{Name: string(rune('A' + i))}
+		g.ID = uint(i + 1)
+		groups = append(groups, g)
+	}
+
+	text, keyboard := groupsPage(groups, 0)
+	if !strings.Contains(text, "page 1 of 2") {
+		t.Errorf("expected the page number in %q", text)
+	}
+	rows := keyboard.InlineKeyboard
+	if len(rows) != groupsPerPage+1 || *rows[0][0].CallbackData != "//group 1" {
+		t.Fatalf("expected a button for each group then Next, got %v", rows)
+	}
+	if nav := rows[len(rows)-1]; len(nav) != 1 || *nav[0].CallbackData != "//groups_page 1" {
+		t.Errorf("expected only Next, got %v", nav)
+	}
+
+	_, keyboard = groupsPage(groups, 5)
+	rows = keyboard.InlineKeyboard
+	if len(rows) != 2 || *rows[0][0].CallbackData != "//group 9" || *rows[1][0].CallbackData != "//groups_page 0" {
+		t.Errorf("expected the last page with Prev, got %v", rows)
+	}
+
+	text, keyboard = groupsPage(groups[:1], 0)
+	if strings.Contains(text, "page") || len(keyboard.InlineKeyboard) != 1 {
+		t.Errorf("expected one page without buttons to change it, got %q %v", text, keyboard.InlineKeyboard)
+	}
+}
+
+func TestGroupCard(t *testing.T) {
+	g := model.InterestGroup{Name: "Ultimate Frisbee", Description: "Throw discs with us!", Contacts: "@a, @b", Schedule: "Tuesdays 8pm"}
+	g.ID = 7
+	text, keyboard := groupCard(g)
+	expected := "*Ultimate Frisbee*\nThrow discs with us!\n\n🗓 Tuesdays 8pm\n👥 Contacts: @a, @b"
+	if text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+	if len(keyboard.InlineKeyboard) != 2 || *keyboard.InlineKeyboard[0][0].CallbackData != "//group_join 7" {
+		t.Errorf("expected join and back buttons, got %v", keyboard.InlineKeyboard)
+	}
+
+	g.Link = "https://t.me/joinchat/abc"
+	_, keyboard = groupCard(g)
+	if len(keyboard.InlineKeyboard) != 3 || *keyboard.InlineKeyboard[1][0].URL != g.Link {
+		t.Errorf("expected a button for the group chat, got %v", keyboard.InlineKeyboard)
+	}
+
+	// names and details with Markdown characters are shown as typed
+	g = model.InterestGroup{Name: "C_S club", Description: "*Code* & [chat]", Contacts: `@some\_name, @other_name`, Schedule: "Fri_night"}
+	text, _ = groupCard(g)
+	expected = "*C_S club*\n\\*Code\\* & \\[chat]\n\n🗓 Fri\\_night\n👥 Contacts: @some\\_name, @other\\_name"
+	if text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
+
+func TestJoinRequestText(t *testing.T) {
+	g := model.InterestGroup{Name: "Tennis"}
+	from := &tgbotapi.User{ID: 42, FirstName: "Sam_", UserName: "sam_lee"}
+	expected := `🙋 [Sam\_](tg://user?id=42) (@sam\_lee) would like to join *Tennis*! Drop them a message to welcome them in.`
+	if got := joinRequestText(from, g); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestBoldMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"Tennis":      "*Tennis*",
+		"snake_case":  "*snake_case*",
+		"2*2=4":       `*2*\**2=4*`,
+		"*Stars*":     `\**Stars*\*`,
+		"Rock & Roll": "*Rock & Roll*",
+	}
+	for text, expected := range tests {
+		if got := boldMarkdown(text); got != expected {
+			t.Errorf("boldMarkdown(%q) = %q, want %q", text, got, expected)
+		}
+	}
+}
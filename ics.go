@@ -0,0 +1,165 @@
+package cinnabot
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/usdevs/cinnabot/utils"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// iCalendar (RFC 5545) export of bookings
+
+// icsTimezone is the TZID of the times in the calendar. Singapore has been at +08:00 without
+// daylight saving since 1982, so a single STANDARD component describes it.
+const icsTimezone = "Asia/Singapore"
+
+const icsVTimezone = "BEGIN:VTIMEZONE\r\n" +
+	"TZID:" + icsTimezone + "\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19820101T000000\r\n" +
+	"TZOFFSETFROM:+0800\r\n" +
+	"TZOFFSETTO:+0800\r\n" +
+	"TZNAME:+08\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n"
+
+// icsMaxLineLen is the most octets in a line before it must be folded
+const icsMaxLineLen = 75
+
+// icsEscaper escapes special characters in TEXT values
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsLine returns a content line, folded so that no line is longer than icsMaxLineLen octets.
+// Lines are only folded between characters, so that multi-byte characters are kept whole.
+func icsLine(name, value string) string {
+	line := name + ":" + value
+	var sb strings.Builder
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > icsMaxLineLen {
+			sb.WriteString("\r\n ")
+			n = 1
+		}
+		sb.WriteRune(r)
+		n += size
+	}
+	sb.WriteString("\r\n")
+	return sb.String()
+}
+
+// icsLocalTime formats a time as a local time in icsTimezone
+func icsLocalTime(t time.Time) string {
+	return t.In(utils.SgLocation()).Format("20060102T150405")
+}
+
+// icsUTCTime formats a time in UTC
+func icsUTCTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsUID returns a unique ID for an event, which stays the same when it is exported again so that
+// calendars update it instead of adding a copy. Events which did not come from Firestore fall back
+// to a hash of their details.
+func icsUID(event Event) string {
+	if event.ID != "" {
+		return path.Base(event.ID) + "@" + uscWebsiteProjectID
+	}
+	hash := sha1.Sum([]byte(event.Venue + "\x00" + event.Name + "\x00" + event.Start.UTC().String()))
+	return fmt.Sprintf("%x@%s", hash[:10], uscWebsiteProjectID)
+}
+
+// formatICS returns the events in spaces as an iCalendar file. now is used as the time the
+// events were exported.
+func formatICS(spaces Spaces, now time.Time) []byte {
+	var sb strings.Builder
+	sb.WriteString("BEGIN:VCALENDAR\r\n")
+	sb.WriteString("VERSION:2.0\r\n")
+	sb.WriteString("PRODID:-//usdevs//Cinnabot//EN\r\n")
+	sb.WriteString("CALSCALE:GREGORIAN\r\n")
+	sb.WriteString("METHOD:PUBLISH\r\n")
+	sb.WriteString(icsLine("X-WR-CALNAME", "USC space bookings"))
+	sb.WriteString(icsLine("X-WR-TIMEZONE", icsTimezone))
+	sb.WriteString(icsVTimezone)
+	for _, space := range spaces.sortByStartDate() {
+		for _, event := range space {
+			sb.WriteString("BEGIN:VEVENT\r\n")
+			sb.WriteString(icsLine("UID", icsUID(event)))
+			sb.WriteString(icsLine("DTSTAMP", icsUTCTime(now)))
+			sb.WriteString(icsLine("DTSTART;TZID="+icsTimezone, icsLocalTime(event.Start)))
+			sb.WriteString(icsLine("DTEND;TZID="+icsTimezone, icsLocalTime(event.End)))
+			sb.WriteString(icsLine("SUMMARY", icsEscaper.Replace(event.Name)))
+			sb.WriteString(icsLine("LOCATION", icsEscaper.Replace(event.Venue)))
+			sb.WriteString("END:VEVENT\r\n")
+		}
+	}
+	sb.WriteString("END:VCALENDAR\r\n")
+	return []byte(sb.String())
+}
+
+// icsFileNameUnsafe matches runs of characters left out of the names of exported files
+var icsFileNameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// icsFileName returns the name of the file for the bookings at a venue, or at every venue if it is empty
+func icsFileName(venue string) string {
+	name := strings.Trim(icsFileNameUnsafe.ReplaceAllString(strings.ToLower(venue), "-"), "-")
+	if name == "" {
+		return "usc-bookings.ics"
+	}
+	return name + "-bookings.ics"
+}
+
+// splitICSArgs splits the arguments of /spaces ics [venue] [period] into the venue, which is
+// empty for every venue, and the period
+func splitICSArgs(args []string) (string, []string) {
+	if len(args) <= 2 {
+		period := true
+		for _, arg := range args {
+			period = period && isSpacesPeriod(arg)
+		}
+		if period {
+			return "", args
+		}
+	}
+	return splitVenueArgs(args)
+}
+
+// spacesICS sends the bookings at the venue in args, or at every venue, as an iCalendar file
+func (cb *Cinnabot) spacesICS(chatID int64, args []string) {
+	now := time.Now()
+	query, period := splitICSArgs(args)
+	predicate, _, description, ok := venuePeriod(period, now)
+	if !ok {
+		cb.SendTextMessage(int(chatID), "Cinnabot was unable to understand your command.\n\n"+spacesHelp)
+		return
+	}
+
+	spaces := venueSpaces(period, now)
+	venue := ""
+	if query != "" {
+		if venue, ok = matchVenue(spaces.venueNames(), query); !ok {
+			cb.SendMessage(NewMessageWithButton("Cinnabot couldn't find that venue. Pick one to see its bookings for the next 7 days.", makeVenueKeyboard(spaces.venueNames()), chatID))
+			return
+		}
+		spaces = Spaces{spaces.space(venue)}
+	}
+	spaces = spaces.filter(predicate)
+
+	where := "every venue"
+	if venue != "" {
+		where = venue
+	}
+	if len(spaces) == 0 {
+		cb.SendTextMessage(int(chatID), fmt.Sprintf("There are no bookings at %s %s.", where, description))
+		return
+	}
+
+	doc := tgbotapi.NewDocumentUpload(chatID, tgbotapi.FileBytes{Name: icsFileName(venue), Bytes: formatICS(spaces, now)})
+	doc.Caption = fmt.Sprintf("Bookings at %s %s. Open the file to add them to your calendar.", where, description)
+	cb.SendMessage(doc)
+}
@@ -0,0 +1,83 @@
+package cinnabot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatICS(t *testing.T) {
+	start := time.Date(2018, 11, 19, 1, 30, 0, 0, time.UTC)
+	spaces := Spaces{Space{{
+		ID:    "projects/usc-website-206715/databases/(default)/documents/events/abc123",
+		Name:  "Band practice; bring drums, amps",
+		Venue: "Chill Room",
+		Start: start,
+		End:   start.Add(2 * time.Hour),
+	}}}
+	ics := string(formatICS(spaces, start))
+
+	for _, line := range []string{
+		"BEGIN:VCALENDAR",
+		"TZID:Asia/Singapore",
+		"UID:abc123@usc-website-206715",
+		"DTSTAMP:20181119T013000Z",
+		"DTSTART;TZID=Asia/Singapore:20181119T093000",
+		"DTEND;TZID=Asia/Singapore:20181119T113000",
+		`SUMMARY:Band practice\; bring drums\, amps`,
+		"LOCATION:Chill Room",
+		"END:VCALENDAR",
+	} {
+		if !strings.Contains(ics, "\r\n"+line+"\r\n") && !strings.HasPrefix(ics, line+"\r\n") {
+			t.Errorf("missing line %q in:\n%s", line, ics)
+		}
+	}
+}
+
+func TestICSLineFolding(t *testing.T) {
+	line := icsLine("SUMMARY", strings.Repeat("é", 100))
+	lines := strings.Split(strings.TrimSuffix(line, "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("line was not folded: %q", line)
+	}
+	var unfolded strings.Builder
+	for i, l := range lines {
+		if len(l) > icsMaxLineLen {
+			t.Errorf("line %d is %d octets long", i, len(l))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(l, " ") {
+				t.Errorf("continuation line %d does not start with a space", i)
+			}
+			l = l[1:]
+		}
+		unfolded.WriteString(l)
+	}
+	if unfolded.String() != "SUMMARY:"+strings.Repeat("é", 100) {
+		t.Errorf("unfolded line is %q", unfolded.String())
+	}
+}
+
+func TestICSUID(t *testing.T) {
+	event := Event{Name: "Talk", Venue: "Cinnamon Lounge", Start: time.Date(2018, 11, 19, 10, 0, 0, 0, time.UTC)}
+	if icsUID(event) != icsUID(event) {
+		t.Error("UID is not stable")
+	}
+	other := event
+	other.Name = "Another talk"
+	if icsUID(event) == icsUID(other) {
+		t.Error("different events have the same UID")
+	}
+}
+
+func TestSplitICSArgs(t *testing.T) {
+	if venue, period := splitICSArgs([]string{"19/11", "21/11"}); venue != "" || len(period) != 2 {
+		t.Errorf("splitICSArgs(dates) = %q, %q", venue, period)
+	}
+	if venue, period := splitICSArgs([]string{"chill", "room", "week"}); venue != "chill room" || len(period) != 1 {
+		t.Errorf("splitICSArgs(venue week) = %q, %q", venue, period)
+	}
+	if name := icsFileName("Chill Room (Level 1)"); name != "chill-room-level-1-bookings.ics" {
+		t.Errorf("icsFileName() = %q", name)
+	}
+}
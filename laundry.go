@@ -149,7 +149,8 @@ func splitByType(machines []machine) ([]washer, []dryer) {
 
 // used by UI
 
-func getAllMachines() ([]level, error) {
+// getAllMachines returns the machines grouped by level. Machines whose keys are in reported are flagged as faulty.
+func getAllMachines(reported map[string]bool) ([]level, error) {
 	levels := make([]level, 0, 2)
 	piData, piErr := getPiData()
 	if piErr != nil {
@@ -160,6 +161,9 @@ func getAllMachines() ([]level, error) {
 		return levels, mErr
 	}
 	machines := toMachines(mData, piData)
+	for i := range machines {
+		machines[i].ReportedFaulty = reported[machines[i].key()]
+	}
 	l9, l17 := splitByLevel(machines)
 	for i, m := range [2][]machine{l9, l17} {
 		var lastSeen time.Time
@@ -227,6 +231,7 @@ const (
 
 	notWorkingStr = " (sensor may not be working)"
 	notCertainStr = " (or less)"
+	faultyStr     = "\n\u26a0\ufe0f reported faulty"
 )
 
 func formatTimeLeft(timeLeft time.Duration) string {
@@ -249,20 +254,31 @@ type machine struct {
 	TimeChanged        time.Time
 	TimeChangedCertain bool
 	LastSeen           time.Time
+	ReportedFaulty     bool
 }
 
 func (m machine) timeLeft(cycleLength time.Duration) time.Duration {
 	return cycleLength - time.Since(m.TimeChanged)
 }
 
-func (m machine) description() string {
-	var ezlinkStr string
+func (m machine) payment() string {
 	if m.Ezlink {
-		ezlinkStr = "ezlink"
-	} else {
-		ezlinkStr = "coin"
+		return "ezlink"
 	}
-	return fmt.Sprintf("*%s (%s)*", m.Name, ezlinkStr)
+	return "coin"
+}
+
+func (m machine) description() string {
+	return fmt.Sprintf("*%s (%s)*", m.Name, m.payment())
+}
+
+// key uniquely identifies a machine. It is used in callback data and to match fault reports to machines.
+func (m machine) key() string {
+	return machineKey(m.Level, m.Name, m.Ezlink)
+}
+
+func machineKey(level int, name string, ezlink bool) string {
+	return fmt.Sprintf("%d:%s:%s", level, name, machine{Ezlink: ezlink}.payment())
 }
 
 type washer machine
@@ -285,6 +301,9 @@ func (w washer) string() string {
 	} else {
 		str += "free"
 	}
+	if w.ReportedFaulty {
+		str += faultyStr
+	}
 	return str
 	// treats the last seen time of each sensor individually
 	// str := machine(w).description() + ": "
@@ -336,6 +355,9 @@ func (d dryer) string() string {
 		// 		str += notWorkingStr
 		// 	}
 	}
+	if d.ReportedFaulty {
+		str += faultyStr
+	}
 	return str
 }
 
@@ -405,9 +427,9 @@ func (l level) string() string {
 	return sb.String()
 }
 
-func laundryMsg() string {
+func laundryMsg(reported map[string]bool) string {
 	lastUpdated := "Last updated: " + time.Now().In(utils.SgLocation()).Format(time.RFC822)
-	levels, err := getAllMachines()
+	levels, err := getAllMachines(reported)
 
 	if err != nil {
 		return "Could not fetch laundry availability.\n" + lastUpdated
@@ -426,12 +448,15 @@ func laundryMsg() string {
 
 func makeLaundryRefreshButton() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Refresh", "//laundry_refresh")),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Refresh", "//laundry_refresh"),
+			tgbotapi.NewInlineKeyboardButtonData("Report problem", "//laundry_report"),
+		),
 	)
 }
 
 func (cb *Cinnabot) LaundryRefresh(qry *Callback) {
-	text := laundryMsg()
+	text := laundryMsg(cb.reportedMachines())
 	refreshButton := makeLaundryRefreshButton()
 	toSend := EditedMessageWithButton(text, refreshButton, qry.ChatID, qry.MsgID)
 	cb.SendMessage(toSend)
@@ -439,7 +464,7 @@ func (cb *Cinnabot) LaundryRefresh(qry *Callback) {
 
 // Laundry checks the washer and dryer availability.
 func (cb *Cinnabot) Laundry(msg *message) {
-	text := laundryMsg()
+	text := laundryMsg(cb.reportedMachines())
 	refreshButton := makeLaundryRefreshButton()
 	toSend := NewMessageWithButton(text, refreshButton, msg.Chat.ID)
 	cb.SendMessage(toSend)
@@ -0,0 +1,291 @@
+package cinnabot
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	cache "github.com/patrickmn/go-cache"
+	"github.com/usdevs/cinnabot/model"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// laundryFault is a type of problem that can be reported for a machine
+type laundryFault struct {
+	Code        string
+	Description string
+}
+
+var laundryFaults = []laundryFault{
+	{"start", "Does not start"},
+	{"payment", "Payment not working"},
+	{"door", "Door jammed"},
+	{"leak", "Leaking water"},
+	{"heat", "Not drying / heating"},
+	{"other", "Something else"},
+}
+
+func faultDescription(code string) string {
+	for _, f := range laundryFaults {
+		if f.Code == code {
+			return f.Description
+		}
+	}
+	return code
+}
+
+// reportedMachines returns the keys of machines with unresolved fault reports
+func (cb *Cinnabot) reportedMachines() map[string]bool {
+	reported := make(map[string]bool)
+	if cb.db == nil {
+		return reported
+	}
+	for _, r := range cb.db.OpenLaundryReports() {
+		reported[machineKey(r.Level, r.Machine, r.Ezlink)] = true
+	}
+	return reported
+}
+
+// pendingReportKey is the cache key used to remember which report a user's next photo belongs to
+func pendingReportKey(userID int) string {
+	return "laundryreport:" + strconv.Itoa(userID)
+}
+
+// reportableMachines returns every machine, in the order they are shown in the machine picker
+func reportableMachines(levels []level) []machine {
+	machines := make([]machine, 0)
+	for _, l := range levels {
+		sortWashers(l.washers)
+		sortDryers(l.dryers)
+		for _, w := range l.washers {
+			machines = append(machines, machine(w))
+		}
+		for _, d := range l.dryers {
+			machines = append(machines, machine(d))
+		}
+	}
+	return machines
+}
+
+// machineRef returns how buttons refer to a machine, eg. "17 1a2b3c4d": its level and a short hash
+// of its key, as machine keys can be too long for Telegram's 64-byte callback data.
+func machineRef(m machine) string {
+	hash := sha1.Sum([]byte(m.key()))
+	return fmt.Sprintf("%d %x", m.Level, hash[:4])
+}
+
+// errMachineGone is returned by pickedMachine if the machine is no longer listed
+var errMachineGone = errors.New("machine is no longer listed")
+
+// pickedMachine returns the machine referred to by the level and hash in args, as sent in callback data
+func pickedMachine(args []string) (machine, error) {
+	if len(args) < 2 {
+		return machine{}, fmt.Errorf("invalid machine reference %q", args)
+	}
+	levels, err := getAllMachines(nil)
+	if err != nil {
+		return machine{}, err
+	}
+	ref := args[0] + " " + args[1]
+	for _, m := range reportableMachines(levels) {
+		if machineRef(m) == ref {
+			return m, nil
+		}
+	}
+	return machine{}, errMachineGone
+}
+
+func makeMachinePickerKeyboard(levels []level) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0)
+	for _, m := range reportableMachines(levels) {
+		kind := "dryer"
+		if m.Washer {
+			kind = "washer"
+		}
+		text := fmt.Sprintf("L%d %s %s (%s)", m.Level, kind, m.Name, m.payment())
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(text, "//laundry_report_machine "+machineRef(m))))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func makeFaultPickerKeyboard(ref string) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(laundryFaults))
+	for _, f := range laundryFaults {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(f.Description, "//laundry_report_fault "+ref+" "+f.Code)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// sendPickedMachineError tells the user why the machine they picked could not be reported
+func (cb *Cinnabot) sendPickedMachineError(qry *Callback, err error) {
+	if err == errMachineGone {
+		cb.SendMessage(EditedMessage("🤖: That machine is no longer listed. Please use /laundry to report it again.", qry.ChatID, qry.MsgID))
+		return
+	}
+	log.Print(err)
+	cb.SendTextMessage(int(qry.ChatID), "Something went wrong while reporting the machine")
+}
+
+// LaundryReport handles the report button on /laundry messages by asking which machine is faulty
+func (cb *Cinnabot) LaundryReport(qry *Callback) {
+	levels, err := getAllMachines(nil)
+	if err != nil {
+		cb.SendTextMessage(int(qry.ChatID), "Could not fetch the list of laundry machines. Please try again later.")
+		return
+	}
+	keyboard := makeMachinePickerKeyboard(levels)
+	if len(keyboard.InlineKeyboard) == 0 {
+		cb.SendTextMessage(int(qry.ChatID), "There are no laundry machines to report.")
+		return
+	}
+	cb.SendMessage(NewMessageWithButton("🤖: Which machine has a problem?", keyboard, qry.ChatID))
+}
+
+// LaundryReportMachine asks for the type of fault after a machine has been picked
+func (cb *Cinnabot) LaundryReportMachine(qry *Callback) {
+	m, err := pickedMachine(qry.Args)
+	if err != nil {
+		cb.sendPickedMachineError(qry, err)
+		return
+	}
+	text := fmt.Sprintf("🤖: What is wrong with *%s (%s)* on level %d?", m.Name, m.payment(), m.Level)
+	cb.SendMessage(EditedMessageWithButton(text, makeFaultPickerKeyboard(machineRef(m)), qry.ChatID, qry.MsgID))
+}
+
+// LaundryReportFault files the report and lets the user attach an optional photo
+func (cb *Cinnabot) LaundryReportFault(qry *Callback) {
+	if len(qry.Args) < 3 {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while reporting the machine")
+		return
+	}
+	fault := qry.Args[2]
+	m, err := pickedMachine(qry.Args[:2])
+	if err != nil {
+		cb.sendPickedMachineError(qry, err)
+		return
+	}
+
+	report := model.LaundryReport{
+		UserID:  qry.From.ID,
+		Level:   m.Level,
+		Machine: m.Name,
+		Ezlink:  m.Ezlink,
+		Fault:   fault,
+	}
+	if err := cb.db.AddLaundryReport(&report); err != nil {
+		log.Print(err)
+		cb.SendTextMessage(int(qry.ChatID), "🤖: Sorry, your report could not be filed. Please try again later.")
+		return
+	}
+
+	text := fmt.Sprintf("🤖: Thanks! Report #%d for *%s (%s)* on level %d has been filed: %s.\n\n"+
+		"If you'd like, send a photo of the problem now. Use /cancel to skip.",
+		report.ID, m.Name, m.payment(), m.Level, faultDescription(fault))
+	cb.SendMessage(EditedMessage(text, qry.ChatID, qry.MsgID))
+
+	userID := strconv.Itoa(qry.From.ID)
+	cb.cache.Set(userID, "/laundryphoto", cache.DefaultExpiration)
+	cb.cache.Set(pendingReportKey(qry.From.ID), report.ID, cache.DefaultExpiration)
+
+	for _, admin := range cb.keys.Admins {
+		cb.SendTextMessage(admin, fmt.Sprintf("🤖: New laundry fault report #%d\n%s\nUse /laundryreports to manage reports.",
+			report.ID, formatLaundryReport(report)))
+	}
+}
+
+// LaundryReportPhoto attaches a photo sent by the user to the report they just filed
+func (cb *Cinnabot) LaundryReportPhoto(msg *message) {
+	if msg.Photo == nil || len(*msg.Photo) == 0 {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Please send a photo, or use /cancel to skip.")
+		cb.cache.Set(strconv.Itoa(msg.From.ID), "/laundryphoto", cache.DefaultExpiration)
+		return
+	}
+
+	cb.cache.Set(strconv.Itoa(msg.From.ID), "", cache.DefaultExpiration)
+	idRaw, ok := cb.cache.Get(pendingReportKey(msg.From.ID))
+	if !ok {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Sorry, I took too long. Please file the report again.")
+		return
+	}
+	cb.cache.Delete(pendingReportKey(msg.From.ID))
+
+	// Telegram sends several sizes of the same photo, the last one being the largest
+	photos := *msg.Photo
+	if err := cb.db.AttachLaundryReportPhoto(idRaw.(uint), photos[len(photos)-1].FileID); err != nil {
+		log.Print(err)
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Could not attach the photo. Your report has still been filed.")
+		return
+	}
+	cb.SendTextMessage(int(msg.Chat.ID), "🤖: Photo attached. Thank you!")
+}
+
+func formatLaundryReport(r model.LaundryReport) string {
+	text := fmt.Sprintf("Level %d, %s (%s): %s", r.Level, r.Machine, machine{Ezlink: r.Ezlink}.payment(), faultDescription(r.Fault))
+	if r.PhotoID != "" {
+		text += " [photo]"
+	}
+	return text
+}
+
+const noLaundryReportsStr = "🤖: There are no open laundry reports."
+
+// laundryReportsMsg lists the reports, with a button to clear each. There must be at least one report.
+func laundryReportsMsg(reports []model.LaundryReport) (string, tgbotapi.InlineKeyboardMarkup) {
+	lines := []string{"🤖: Open laundry reports:"}
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(reports))
+	for _, r := range reports {
+		lines = append(lines, fmt.Sprintf("#%d %s", r.ID, formatLaundryReport(r)))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Clear #%d", r.ID), fmt.Sprintf("//laundry_clear %d", r.ID)),
+		))
+	}
+	return strings.Join(lines, "\n"), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// LaundryReports lists open laundry fault reports. Only available to admins.
+func (cb *Cinnabot) LaundryReports(msg *message) {
+	if !cb.isAdmin(msg.From.ID) {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Only admins can manage laundry reports.")
+		return
+	}
+	reports := cb.db.OpenLaundryReports()
+	if len(reports) == 0 {
+		cb.SendTextMessage(int(msg.Chat.ID), noLaundryReportsStr)
+		return
+	}
+	text, keyboard := laundryReportsMsg(reports)
+	cb.SendMessage(NewMessageWithButton(text, keyboard, msg.Chat.ID))
+	for _, r := range reports {
+		if r.PhotoID != "" {
+			photo := tgbotapi.NewPhotoShare(msg.Chat.ID, r.PhotoID)
+			photo.Caption = fmt.Sprintf("Report #%d", r.ID)
+			cb.SendMessage(photo)
+		}
+	}
+}
+
+// LaundryClear resolves a laundry fault report. Only available to admins.
+func (cb *Cinnabot) LaundryClear(qry *Callback) {
+	if !cb.isAdmin(qry.From.ID) {
+		cb.SendTextMessage(int(qry.ChatID), "🤖: Only admins can manage laundry reports.")
+		return
+	}
+	id, err := strconv.ParseUint(qry.GetArgString(), 10, 32)
+	if err != nil {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while clearing the report")
+		return
+	}
+	if err := cb.db.ResolveLaundryReport(uint(id), qry.From.ID); err != nil {
+		log.Print(err)
+	}
+	reports := cb.db.OpenLaundryReports()
+	if len(reports) == 0 {
+		cb.SendMessage(EditedMessage(noLaundryReportsStr, qry.ChatID, qry.MsgID))
+		return
+	}
+	text, keyboard := laundryReportsMsg(reports)
+	cb.SendMessage(EditedMessageWithButton(text, keyboard, qry.ChatID, qry.MsgID))
+}
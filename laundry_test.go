@@ -1,6 +1,7 @@
 package cinnabot
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -123,3 +124,35 @@ func TestStrings(t *testing.T) {
 		t.Log(m.string())
 	}
 }
+
+func TestMachinePickerKeyboard(t *testing.T) {
+	long := strings.Repeat("Very long machine name ", 5)
+	levels := []level{{
+		washers: []washer{{Name: long, Level: 9, Washer: true}},
+		dryers:  []dryer{{Name: "Dryer 1", Level: 9}},
+	}}
+	keyboard := makeMachinePickerKeyboard(levels)
+	if len(keyboard.InlineKeyboard) != 2 {
+		t.Fatalf("keyboard has %d rows, want 2", len(keyboard.InlineKeyboard))
+	}
+	ref := machineRef(machine{Name: "Dryer 1", Level: 9})
+	if data := *keyboard.InlineKeyboard[1][0].CallbackData; data != "//laundry_report_machine "+ref {
+		t.Errorf("dryer button has callback data %q", data)
+	}
+	if ref == machineRef(machine{Name: "Dryer 2", Level: 9}) {
+		t.Errorf("machines on the same level have the same reference %q", ref)
+	}
+	for _, row := range makeFaultPickerKeyboard(machineRef(machine{Name: long, Level: 9, Washer: true})).InlineKeyboard {
+		if data := *row[0].CallbackData; len(data) > maxCallbackDataLen {
+			t.Errorf("callback data %q is too long", data)
+		}
+	}
+}
+
+func TestReportedFaultyString(t *testing.T) {
+	w := washer{Name: "A", Level: 17, Washer: true, ReportedFaulty: true}
+	expected := "*A (coin)*: free" + faultyStr
+	if w.string() != expected {
+		t.Errorf("expected %q, got %q", expected, w.string())
+	}
+}
@@ -33,13 +33,26 @@ func main() {
 	cb.AddFunction("/stats", cb.GetStats)
 
 	cb.AddFunction("/map", cb.NUSMap)
+	cb.AddFunction("/where", cb.Where)
 	cb.AddFunction("/resources", cb.Resources)
 	cb.AddFunction("/publicbus", cb.PublicBus)
 	cb.AddFunction("/nusbus", cb.NUSBus)
+	cb.AddFunction("/mybus", cb.MyBus)
+	cb.AddFunction("/busalerts", cb.BusAlerts)
+	cb.AddFunction("/route", cb.Route)
 	cb.AddFunction("/weather", cb.Weather)
+	cb.AddFunction("/rainalerts", cb.RainAlerts)
+	cb.AddFunction("/air", cb.Air)
+	cb.AddFunction("/places", cb.Places)
 	cb.AddFunction("/map", cb.NUSMap)
 	cb.AddFunction("/spaces", cb.Spaces)
+	cb.AddFunction("/free", cb.Free)
 	cb.AddFunction("/laundry", cb.Laundry)
+	cb.AddFunction("/laundryphoto", cb.LaundryReportPhoto)
+	cb.AddFunction("/laundryreports", cb.LaundryReports)
+	cb.AddFunction("/editresources", cb.EditResources)
+	cb.AddFunction("/groups", cb.Groups)
+	cb.AddFunction("/editgroups", cb.EditGroups)
 
 	cb.AddFunction("/feedback", cb.Feedback)
 	cb.AddFunction("/dhsurvey", cb.DHSurvey)
@@ -55,7 +68,39 @@ func main() {
 	cb.AddHandler("//nusbus_refresh", cb.NUSBusRefresh_Buttons)
 	cb.AddHandler("//nusbus_loc_refresh", cb.NUSBusRefresh_Location)
 	cb.AddHandler("//publicbus_refresh", cb.PublicBusRefresh)
+	cb.AddHandler("//publicbus_stop", cb.PublicBusStop)
+	cb.AddHandler("//mybus_refresh", cb.MyBusRefresh)
+	cb.AddHandler("//bus_star", cb.BusStar)
+	cb.AddHandler("//busalert", cb.BusAlert)
+	cb.AddHandler("//busalert_stop", cb.BusAlertStop)
+	cb.AddHandler("//busalert_svc", cb.BusAlertService)
+	cb.AddHandler("//busalert_set", cb.BusAlertSet)
+	cb.AddHandler("//busalert_cancel", cb.BusAlertCancel)
+	cb.AddHandler("//route_refresh", cb.RouteRefresh)
+	cb.AddHandler("//rain_sub", cb.RainAlertSubscribe)
+	cb.AddHandler("//air_refresh", cb.AirRefresh)
+	cb.AddHandler("//air_alerts", cb.AirAlerts)
+	cb.AddHandler("//air_alert_set", cb.AirAlertSet)
+	cb.AddHandler("//place_del", cb.PlaceDelete)
+	cb.AddHandler("//where", cb.WhereVenue)
+	cb.AddHandler("//spaces_venue", cb.SpacesVenue)
+	cb.AddHandler("//spaces_watch", cb.SpacesWatch)
+	cb.AddHandler("//spaces_remind", cb.SpacesRemind)
+	cb.AddHandler("//resources_page", cb.ResourcesPage)
+	cb.AddHandler("//groups_page", cb.GroupsPage)
+	cb.AddHandler("//group", cb.GroupShow)
+	cb.AddHandler("//group_join", cb.GroupJoin)
 	cb.AddHandler("//laundry_refresh", cb.LaundryRefresh)
+	cb.AddHandler("//laundry_report", cb.LaundryReport)
+	cb.AddHandler("//laundry_report_machine", cb.LaundryReportMachine)
+	cb.AddHandler("//laundry_report_fault", cb.LaundryReportFault)
+	cb.AddHandler("//laundry_clear", cb.LaundryClear)
+
+	cb.Every(cinnabot.BusAlertInterval, cb.CheckBusAlerts)
+	cb.Every(cinnabot.RainAlertInterval, cb.CheckRainAlerts)
+	cb.Every(cinnabot.AirAlertInterval, cb.CheckAirAlerts)
+	cb.Every(cinnabot.VenueWatchInterval, cb.CheckVenueWatches)
+	cb.Every(cinnabot.EventReminderInterval, cb.CheckEventReminders)
 
 	updates := cb.Listen(60)
 	log.Println("Listening...")
@@ -0,0 +1,181 @@
+package cinnabot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/usdevs/cinnabot/utils"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// campusMap is a map of part of NUS which /map can send
+type campusMap struct {
+	Key       string   `json:"key"`
+	Aliases   []string `json:"aliases"`
+	Name      string   `json:"name"`  // shown on the keyboard button
+	Image     string   `json:"image"` // path relative to the working directory, or a URL
+	NUSMods   string   `json:"nusmods"`
+	Note      string   `json:"note"`
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+}
+
+// names returns the lower case names the map can be asked for by
+func (m campusMap) names() []string {
+	names := []string{m.Key, strings.ToLower(m.Name)}
+	for _, alias := range m.Aliases {
+		names = append(names, strings.ToLower(alias))
+	}
+	return names
+}
+
+// mapRegistry holds the maps which /map can send, and the Telegram file_ids of their images once
+// they have been uploaded, so that each image is only uploaded once
+type mapRegistry struct {
+	maps []campusMap
+
+	mu      sync.Mutex
+	fileIDs map[string]string // key of map to file_id
+}
+
+// readMaps reads the maps for /map from a JSON file (eg. maps.json)
+func readMaps(path string) (*mapRegistry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var maps struct {
+		Maps []campusMap `json:"maps"`
+	}
+	if err := json.Unmarshal(data, &maps); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &mapRegistry{maps: maps.Maps, fileIDs: make(map[string]string)}, nil
+}
+
+// find returns the map with query as its key, name or an alias, ignoring case
+func (r *mapRegistry) find(query string) (campusMap, bool) {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	for _, m := range r.maps {
+		for _, name := range m.names() {
+			if name == query {
+				return m, true
+			}
+		}
+	}
+	return campusMap{}, false
+}
+
+// nearest returns the map whose centre is nearest to a point, if it is within radius metres
+func (r *mapRegistry) nearest(lat, lng, radius float64) (campusMap, bool) {
+	var nearest campusMap
+	best := radius
+	found := false
+	for _, m := range r.maps {
+		if d := utils.Haversine(lat, lng, m.Latitude, m.Longitude); d <= best {
+			nearest, best, found = m, d, true
+		}
+	}
+	return nearest, found
+}
+
+// args returns the first words of the names of every map, for CheckArgCmdPair. The registry
+// may be nil if the maps could not be read.
+func (r *mapRegistry) args() []string {
+	args := []string{""}
+	if r == nil {
+		return args
+	}
+	for _, m := range r.maps {
+		for _, name := range m.names() {
+			if words := strings.Fields(name); len(words) > 0 {
+				args = append(args, words[0])
+			}
+		}
+	}
+	return args
+}
+
+// keyboard returns a reply keyboard with a button for each map, two to a row
+func (r *mapRegistry) keyboard() tgbotapi.ReplyKeyboardMarkup {
+	var rows [][]tgbotapi.KeyboardButton
+	for i := 0; i < len(r.maps); i += 2 {
+		row := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(r.maps[i].Name))
+		if i+1 < len(r.maps) {
+			row = append(row, tgbotapi.NewKeyboardButton(r.maps[i+1].Name))
+		}
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewReplyKeyboard(rows...)
+}
+
+// photo returns the message for sending the image of a map, reusing its file_id if it has been sent before
+func (r *mapRegistry) photo(chatID int64, m campusMap) tgbotapi.PhotoConfig {
+	r.mu.Lock()
+	fileID, ok := r.fileIDs[m.Key]
+	r.mu.Unlock()
+	switch {
+	case ok:
+		return tgbotapi.NewPhotoShare(chatID, fileID)
+	case strings.HasPrefix(m.Image, "http://") || strings.HasPrefix(m.Image, "https://"):
+		return tgbotapi.NewPhotoShare(chatID, m.Image)
+	default:
+		return tgbotapi.NewPhotoUpload(chatID, m.Image)
+	}
+}
+
+// sent records the file_id of a map's image from the message it was sent in
+func (r *mapRegistry) sent(m campusMap, msg tgbotapi.Message) {
+	if msg.Photo == nil || len(*msg.Photo) == 0 {
+		return
+	}
+	// Sizes are in increasing order, and the largest is the original
+	photos := *msg.Photo
+	r.mu.Lock()
+	r.fileIDs[m.Key] = photos[len(photos)-1].FileID
+	r.mu.Unlock()
+}
+
+// mapText is the message sent with the image of a map
+func mapText(firstName string, m campusMap) string {
+	text := "🤖: Hey " + firstName + ", heard of NUSMODs ?\n\n " +
+		"It is a student intiative made to improve the lives of students!" + "\n" +
+		"They also have a function to help you find your way around!\n Click on the link below!\n\n" +
+		m.NUSMods
+	if m.Note != "" {
+		text += "\n\n " + m.Note
+	}
+	return text
+}
+
+// sendMap sends the image of a map
+func (cb *Cinnabot) sendMap(chatID int64, m campusMap) {
+	sent, err := cb.bot.Send(cb.maps.photo(chatID, m))
+	if err != nil {
+		cb.log.Printf("cannot send map %s: %s", m.Key, err)
+		return
+	}
+	cb.maps.sent(m, sent)
+}
+
+// NUSMap sends a map of part of NUS, with a link to NUSMods to find venues
+func (cb *Cinnabot) NUSMap(msg *message) {
+	if cb.maps == nil {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Sorry, maps are unavailable right now.")
+		return
+	}
+	m, ok := cb.maps.find(msg.GetArgString())
+	if len(msg.Args) == 0 || !ok {
+		// Message sent upon user using /map command
+		replyMsg := tgbotapi.NewMessage(int64(msg.Chat.ID), "🤖: Hey "+msg.From.FirstName+"! Where are you?\n\n")
+		replyMsg.ReplyMarkup = cb.maps.keyboard()
+		cb.SendMessage(replyMsg)
+		return
+	}
+
+	cb.sendMap(msg.Chat.ID, m)
+	cb.SendTextMessage(int(msg.Chat.ID), mapText(msg.From.FirstName, m))
+}
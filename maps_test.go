@@ -0,0 +1,80 @@
+package cinnabot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+func TestMapsData(t *testing.T) {
+	maps, err := readMaps("main/maps.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := make(map[string]string)
+	for _, m := range maps.maps {
+		for _, name := range m.names() {
+			if key, ok := owner[name]; ok && key != m.Key {
+				t.Errorf("maps %s and %s are both called %q", key, m.Key, name)
+			}
+			owner[name] = m.Key
+		}
+		if m.Name == "" || m.NUSMods == "" {
+			t.Errorf("map %s has no name or NUSMods link", m.Key)
+		}
+		if !strings.HasPrefix(m.Image, "https://") {
+			// Images are read relative to main/, where the bot is run
+			if _, err := os.Stat(filepath.Join("main", m.Image)); err != nil {
+				t.Errorf("map %s: %s", m.Key, err)
+			}
+		}
+	}
+}
+
+func TestMapRegistry(t *testing.T) {
+	maps := &mapRegistry{
+		maps: []campusMap{
+			{Key: "nus", Name: "NUS Map", Image: "https://example.com/nus.jpg"},
+			{Key: "engin", Name: "Yih/Engin", Aliases: []string{"Engineering"}, Image: "maps/engin.png"},
+			{Key: "comp", Name: "Comp", Image: "maps/comp.png"},
+		},
+		fileIDs: make(map[string]string),
+	}
+
+	for _, query := range []string{"engin", "Yih/Engin", "engineering", " ENGINEERING "} {
+		if m, ok := maps.find(query); !ok || m.Key != "engin" {
+			t.Errorf("find(%q): expected engin, got %q %v", query, m.Key, ok)
+		}
+	}
+	if m, ok := maps.find("nus map"); !ok || m.Key != "nus" {
+		t.Errorf("expected the button text to find nus, got %q %v", m.Key, ok)
+	}
+	if _, ok := maps.find("cenlib"); ok {
+		t.Error("expected cenlib not to be found")
+	}
+
+	args := strings.Join(maps.args(), ",")
+	if args != ",nus,nus,engin,yih/engin,engineering,comp,comp" {
+		t.Errorf("unexpected args %s", args)
+	}
+	if rows := maps.keyboard().Keyboard; len(rows) != 2 || rows[0][1].Text != "Yih/Engin" {
+		t.Errorf("unexpected keyboard %v", rows)
+	}
+
+	// Images are uploaded the first time, then sent by file_id
+	comp, _ := maps.find("comp")
+	if photo := maps.photo(1, comp); photo.UseExisting || photo.File != "maps/comp.png" {
+		t.Errorf("expected comp to be uploaded, got %+v", photo.BaseFile)
+	}
+	maps.sent(comp, tgbotapi.Message{Photo: &[]tgbotapi.PhotoSize{{FileID: "small"}, {FileID: "large"}}})
+	if photo := maps.photo(1, comp); !photo.UseExisting || photo.FileID != "large" {
+		t.Errorf("expected comp to be sent by file_id, got %+v", photo.BaseFile)
+	}
+	nus, _ := maps.find("nus")
+	if photo := maps.photo(1, nus); !photo.UseExisting || photo.FileID != "https://example.com/nus.jpg" {
+		t.Errorf("expected nus to be sent by URL, got %+v", photo.BaseFile)
+	}
+}
@@ -0,0 +1,172 @@
+package cinnabot
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageLength is the most characters Telegram allows in a message, counted in UTF-16 code units
+const maxMessageLength = 4096
+
+// telegramLength returns the length of text as Telegram counts it, in UTF-16 code units
+func telegramLength(text string) int {
+	n := 0
+	for _, r := range text {
+		if r > 0xFFFF {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// markdownCloser returns the text which ends a Markdown entity started by opener. Links, which
+// start with "[", end after the closing bracket of their URL.
+func markdownCloser(opener string) string {
+	if opener == "[" {
+		return ")"
+	}
+	return opener
+}
+
+// cutPoints are the last places text can be cut before a limit, outside any Markdown entity.
+// Each is 0 if there is no such place.
+type cutPoints struct {
+	paragraph int // before a blank line
+	line      int // before a newline
+	word      int // before a space
+	any       int // between any two characters
+}
+
+// scanCutPoints finds the last places text can be cut so that the part before the cut is at most
+// limit long. It also returns the entity open at the limit, if any.
+func scanCutPoints(text string, limit int, markdown bool) (cutPoints, string) {
+	var points cutPoints
+	entity := ""
+	n := 0 // length of text[:i]
+	for i := 0; i < len(text); {
+		if entity == "" {
+			// text[:i] is outside any entity, so it can be cut here
+			points.any = i
+			switch {
+			case strings.HasPrefix(text[i:], "\n\n"):
+				points.paragraph = i
+			case text[i] == '\n':
+				points.line = i
+			case text[i] == ' ':
+				points.word = i
+			}
+		}
+
+		next, nextEntity := i, entity
+		if markdown {
+			next, nextEntity = markdownStep(text, i, entity)
+		} else {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			next += size
+		}
+		n += telegramLength(text[i:next])
+		if n > limit {
+			// the entity is still open at the limit, even if this character would close it
+			break
+		}
+		i, entity = next, nextEntity
+	}
+	return points, entity
+}
+
+// markdownStep reads the character at text[i], which is inside entity if it is not empty. It
+// returns where the next character starts, and the entity open after this one, eg. "*" after the
+// start of bold text or "" after its end. An escaped character is read together with its backslash.
+func markdownStep(text string, i int, entity string) (int, string) {
+	r, size := utf8.DecodeRuneInString(text[i:])
+	next := i + size
+	switch {
+	case entity == "" && r == '\\' && next < len(text):
+		_, escaped := utf8.DecodeRuneInString(text[next:])
+		return next + escaped, ""
+	case entity == "" && strings.HasPrefix(text[i:], "```"):
+		return i + 3, "```"
+	case entity == "" && strings.ContainsRune("*_`[", r):
+		return next, string(r)
+	case entity == "[" && r == ']' && !strings.HasPrefix(text[next:], "("):
+		// not a link after all
+		return next, ""
+	case entity != "" && strings.HasPrefix(text[i:], markdownCloser(entity)):
+		return i + len(markdownCloser(entity)), ""
+	}
+	return next, entity
+}
+
+// cutInEntity returns where to cut text which starts with an entity longer than limit. The cut
+// is on a character boundary, leaving space to close the entity.
+func cutInEntity(text string, limit int, entity string) int {
+	cut, n := 0, 0
+	for i, r := range text {
+		width := telegramLength(string(r))
+		if n+width+len(markdownCloser(entity)) > limit {
+			break
+		}
+		n += width
+		cut = i + utf8.RuneLen(r)
+	}
+	return cut
+}
+
+// markdownCut returns where to cut text so that the part before the cut is at most limit long.
+// If the text is Markdown, the cut is made outside entities where possible, preferring the end
+// of a paragraph, then a line, then a word, as long as that keeps at least half the limit. open
+// is the entity the cut is inside, if there is no safe place to cut, so that it can be closed and
+// reopened.
+func markdownCut(text string, limit int, markdown bool) (cut int, open string) {
+	points, entity := scanCutPoints(text, limit, markdown)
+	for _, cut := range []int{points.paragraph, points.line, points.word} {
+		if cut > 0 && telegramLength(text[:cut]) >= limit/2 {
+			return cut, ""
+		}
+	}
+	if points.any > 0 {
+		return points.any, ""
+	}
+	return cutInEntity(text, limit, entity), entity
+}
+
+// plainLink rewrites the link at the start of text as plain text, eg. "label (url)". If text does
+// not start with a whole link, its "[" is escaped instead.
+func plainLink(text string) string {
+	end := strings.Index(text, "](")
+	if end < 0 {
+		return `\` + text
+	}
+	close := strings.Index(text[end:], ")")
+	if close < 0 {
+		return `\` + text
+	}
+	label, url := text[1:end], text[end+2:end+close]
+	return escapeMarkdown(label) + " (" + escapeMarkdown(url) + ")" + text[end+close+1:]
+}
+
+// splitMessage splits text into parts which each fit in a Telegram message. In Markdown, entities
+// such as *bold* are kept whole, or closed and reopened if they are too long to fit in one part.
+// Links cannot be reopened, so one which is too long is shown as plain text instead.
+func splitMessage(text string, limit int, markdown bool) []string {
+	parts := make([]string, 0, 1)
+	for telegramLength(text) > limit {
+		cut, open := markdownCut(text, limit, markdown)
+		if open == "[" {
+			text = plainLink(text)
+			continue
+		}
+		part, rest := text[:cut], text[cut:]
+		if open != "" {
+			part += markdownCloser(open)
+			rest = open + rest
+		}
+		if part = strings.TrimRight(part, " \n"); part != "" {
+			parts = append(parts, part)
+		}
+		text = strings.TrimLeft(rest, " \n")
+	}
+	return append(parts, text)
+}
@@ -0,0 +1,134 @@
+package cinnabot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// checkParts checks that parts fit in limit and have balanced Markdown
+func checkParts(t *testing.T, parts []string, limit int) {
+	t.Helper()
+	for i, part := range parts {
+		if telegramLength(part) > limit {
+			t.Errorf("part %d is %d long: %q", i, telegramLength(part), part)
+		}
+		if strings.Count(part, "*")%2 != 0 {
+			t.Errorf("part %d has unbalanced bold: %q", i, part)
+		}
+	}
+}
+
+func TestSplitMessageParagraphs(t *testing.T) {
+	event := "*Band practice:* 03PM to 05PM, Mon 19 Nov 18\n\n"
+	text := strings.Repeat(event, 10)
+	parts := splitMessage(text, 3*len(event), true)
+	checkParts(t, parts, 3*len(event))
+	if len(parts) != 4 {
+		t.Fatalf("got %d parts, want 4: %q", len(parts), parts)
+	}
+	if strings.TrimSpace(strings.Join(parts, "\n\n")) != strings.TrimSpace(text) {
+		t.Error("text was lost when splitting")
+	}
+}
+
+func TestSplitMessageEntities(t *testing.T) {
+	text := "word word *bold text which is long* and [a link](https://example.com) end"
+	parts := splitMessage(text, 35, true)
+	checkParts(t, parts, 35)
+	for _, part := range parts {
+		if strings.Contains(part, "[a link") && !strings.Contains(part, "(https://example.com)") {
+			t.Errorf("link was split: %q", part)
+		}
+	}
+
+	// an entity longer than the limit is closed and reopened
+	parts = splitMessage("*"+strings.Repeat("a", 30)+"*", 12, true)
+	checkParts(t, parts, 12)
+	if parts[0] != "*"+strings.Repeat("a", 10)+"*" {
+		t.Errorf("first part is %q", parts[0])
+	}
+
+	// a link longer than the limit is cut before, and shown as plain text
+	parts = splitMessage("see ["+strings.Repeat("a", 30)+"](https://example.com/a_b) end", 20, true)
+	checkParts(t, parts, 20)
+	if parts[0] != "see" {
+		t.Errorf("first part is %q, want the text before the link", parts[0])
+	}
+	for i, part := range parts[1:] {
+		if strings.ContainsAny(part, "[]") || strings.Contains(part, "/a_b") {
+			t.Errorf("part %d has unescaped Markdown: %q", i+1, part)
+		}
+	}
+
+	// the entity is still open if its closer does not fit
+	parts = splitMessage("```"+strings.Repeat("a", 20)+"``` b", 12, true)
+	for i, part := range parts[:len(parts)-1] {
+		if !strings.HasPrefix(part, "```") || !strings.HasSuffix(part, "```") {
+			t.Errorf("part %d does not keep the pre block whole: %q", i, part)
+		}
+	}
+
+	// plain text has no entities
+	parts = splitMessage("a*b c*d e*f", 4, false)
+	if parts[0] != "a*b" {
+		t.Errorf("plain text was split at %q", parts[0])
+	}
+}
+
+func TestTelegramLength(t *testing.T) {
+	if n := telegramLength("🤖: é"); n != 5 {
+		t.Errorf("telegramLength() = %d, want 5", n)
+	}
+}
+
+func TestSendLongMessage(t *testing.T) {
+	mb := mockBot{}
+	cb := Cinnabot{bot: &mb}
+	mb.On("Send", mock.Anything).Return(nil)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Next", "//next")))
+	text := strings.Repeat("*Band practice:* 03PM to 05PM, Mon 19 Nov 18\n\n", 200)
+	cb.SendMessage(NewMessageWithButton(text, keyboard, 1))
+
+	calls := mb.Calls
+	if len(calls) < 2 {
+		t.Fatalf("sent %d messages, want the text split", len(calls))
+	}
+	for i, call := range calls {
+		msg := call.Arguments.Get(0).(tgbotapi.MessageConfig)
+		if telegramLength(msg.Text) > maxMessageLength {
+			t.Errorf("message %d is too long", i)
+		}
+		if hasKeyboard := msg.ReplyMarkup != nil; hasKeyboard != (i == len(calls)-1) {
+			t.Errorf("message %d has keyboard %v", i, msg.ReplyMarkup)
+		}
+	}
+}
+
+func TestSendLongEdit(t *testing.T) {
+	mb := mockBot{}
+	cb := Cinnabot{bot: &mb}
+	mb.On("Send", mock.Anything).Return(nil)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Refresh", "//refresh")))
+	text := strings.Repeat("*Band practice:* 03PM to 05PM, Mon 19 Nov 18\n\n", 200)
+	cb.SendMessage(EditedMessageWithButton(text, keyboard, 1, 2))
+
+	calls := mb.Calls
+	if len(calls) < 2 {
+		t.Fatalf("sent %d messages, want the text split", len(calls))
+	}
+	edit, ok := calls[0].Arguments.Get(0).(tgbotapi.EditMessageTextConfig)
+	if !ok || edit.MessageID != 2 || edit.ReplyMarkup == nil || telegramLength(edit.Text) > maxMessageLength {
+		t.Errorf("expected the message to be edited to the first part with its buttons, got %+v", calls[0].Arguments.Get(0))
+	}
+	for i, call := range calls[1:] {
+		msg := call.Arguments.Get(0).(tgbotapi.MessageConfig)
+		if telegramLength(msg.Text) > maxMessageLength || msg.ReplyMarkup != nil || msg.ChatID != 1 {
+			t.Errorf("part %d was sent as %+v", i+1, msg)
+		}
+	}
+}
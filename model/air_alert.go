@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+)
+
+// AirAlert is a request by a user to be messaged when the PSI in a region reaches a threshold.
+type AirAlert struct {
+	UserID    int `gorm:"primary_key;auto_increment:false"`
+	UpdatedAt time.Time
+	Region    string // eg. "west"
+	Threshold int    // 24-hour PSI
+}
+
+// AirAlerts returns the air quality alerts of every user.
+func (db *Database) AirAlerts() []AirAlert {
+	var alerts []AirAlert
+	db.Find(&alerts)
+	return alerts
+}
+
+// UserAirAlert returns the user's air quality alert, if they have one.
+func (db *Database) UserAirAlert(userID int) (AirAlert, bool) {
+	var alert AirAlert
+	if err := db.Where("user_id = ?", userID).First(&alert).Error; err != nil {
+		return AirAlert{}, false
+	}
+	return alert, true
+}
+
+// SaveAirAlert sets the user's air quality alert, replacing any existing one.
+func (db *Database) SaveAirAlert(userID int, region string, threshold int) error {
+	return db.Save(&AirAlert{UserID: userID, Region: region, Threshold: threshold}).Error
+}
+
+// DeleteAirAlert removes the user's air quality alert.
+func (db *Database) DeleteAirAlert(userID int) error {
+	return db.Where("user_id = ?", userID).Delete(&AirAlert{}).Error
+}
@@ -18,6 +18,44 @@ type DataGroup interface {
 	UpdateTag(id int, tag string, flag string) error
 	CountUsersAndMessages(period string) (int, int)
 	GetMostUsedCommand(period string) string
+	AddLaundryReport(report *LaundryReport) error
+	OpenLaundryReports() []LaundryReport
+	AttachLaundryReportPhoto(id uint, fileID string) error
+	ResolveLaundryReport(id uint, adminID int) error
+	FavouriteStops(userID int) []FavouriteStop
+	ToggleFavouriteStop(userID int, kind, code, name string) (bool, error)
+	SavedWeatherArea(userID int) string
+	SaveWeatherArea(userID int, area string) error
+	AirAlerts() []AirAlert
+	UserAirAlert(userID int) (AirAlert, bool)
+	SaveAirAlert(userID int, region string, threshold int) error
+	DeleteAirAlert(userID int) error
+	Places(userID int) []Place
+	SavePlace(userID int, name string, lat, lng float64) error
+	DeletePlace(userID int, name string) error
+	Resources(category string) []Resource
+	AddResource(adminID int, category, name, value string) error
+	EditResource(adminID int, category, name, value string) error
+	DeleteResource(adminID int, category, name string) error
+	ReplaceResources(adminID int, resources []Resource) error
+	ResourceChanges(limit int) []ResourceChange
+	InterestGroups() []InterestGroup
+	InterestGroup(id uint) (InterestGroup, error)
+	AddInterestGroup(adminID int, group InterestGroup) error
+	EditInterestGroup(adminID int, name, field, value string) error
+	DeleteInterestGroup(adminID int, name string) error
+	AddJoinRequest(groupID uint, userID int) (bool, error)
+	DeleteJoinRequest(groupID uint, userID int) error
+	UsersByUserName(names []string) []User
+	VenueWatches() []VenueWatch
+	UserVenueWatches(userID int) []VenueWatch
+	ToggleVenueWatch(userID int, venue string) (bool, error)
+	EventReminders() []EventReminder
+	UserEventReminders(userID int) []EventReminder
+	ToggleEventReminder(reminder EventReminder) (bool, error)
+	UpdateEventReminders(eventID, name, venue string, start time.Time) error
+	DeleteEventReminder(id uint) error
+	DeleteEventReminders(eventID string) error
 }
 
 type Database struct {
@@ -43,6 +81,50 @@ func InitializeDB() *Database {
 		db.CreateTable(Feedback{})
 	}
 
+	if !db.HasTable(LaundryReport{}) {
+		db.CreateTable(LaundryReport{})
+	}
+
+	if !db.HasTable(FavouriteStop{}) {
+		db.CreateTable(FavouriteStop{})
+	}
+
+	if !db.HasTable(WeatherArea{}) {
+		db.CreateTable(WeatherArea{})
+	}
+
+	if !db.HasTable(AirAlert{}) {
+		db.CreateTable(AirAlert{})
+	}
+
+	if !db.HasTable(Place{}) {
+		db.CreateTable(Place{})
+	}
+
+	if !db.HasTable(Resource{}) {
+		db.CreateTable(Resource{})
+	}
+
+	if !db.HasTable(ResourceChange{}) {
+		db.CreateTable(ResourceChange{})
+	}
+
+	if !db.HasTable(InterestGroup{}) {
+		db.CreateTable(InterestGroup{})
+	}
+
+	if !db.HasTable(JoinRequest{}) {
+		db.CreateTable(JoinRequest{})
+	}
+
+	if !db.HasTable(VenueWatch{}) {
+		db.CreateTable(VenueWatch{})
+	}
+
+	if !db.HasTable(EventReminder{}) {
+		db.CreateTable(EventReminder{})
+	}
+
 	database := &Database{db}
 
 	return database
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// FavouriteStop is a bus stop starred by a user.
+type FavouriteStop struct {
+	gorm.Model
+	UserID int
+	Kind   string // "nus" for NUS shuttle stops, "public" for public bus stops
+	Code   string
+	Name   string
+}
+
+// FavouriteStops returns the stops starred by the user, in the order they were starred.
+func (db *Database) FavouriteStops(userID int) []FavouriteStop {
+	var stops []FavouriteStop
+	db.Where("user_id = ?", userID).Order("created_at").Find(&stops)
+	return stops
+}
+
+// ToggleFavouriteStop stars the stop for the user if it is not starred, and unstars it otherwise.
+// Returns true if the stop is now starred.
+func (db *Database) ToggleFavouriteStop(userID int, kind, code, name string) (bool, error) {
+	var existing FavouriteStop
+	err := db.Where("user_id = ? AND kind = ? AND code = ?", userID, kind, code).First(&existing).Error
+	if err == nil {
+		return false, db.Unscoped().Delete(&existing).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+	return true, db.Create(&FavouriteStop{UserID: userID, Kind: kind, Code: code, Name: name}).Error
+}
@@ -0,0 +1,132 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInterestGroupExists is returned when adding an interest group with the same name as another.
+var ErrInterestGroupExists = errors.New("interest group already exists")
+
+// InterestGroup is a resident-run group, eg. a sports team. Contacts are the Telegram handles of
+// the people to ask about joining.
+type InterestGroup struct {
+	gorm.Model
+	Name        string
+	Description string
+	Contacts    string
+	Schedule    string // eg. "Tuesdays 8pm at the MPSH"
+	Link        string // invite link to the group's Telegram chat
+}
+
+// Fields returns the fields of the group which admins can edit, by the name of their column.
+func (g InterestGroup) Fields() map[string]string {
+	return map[string]string{
+		"description": g.Description,
+		"contacts":    g.Contacts,
+		"schedule":    g.Schedule,
+		"link":        g.Link,
+	}
+}
+
+// JoinRequest records that a user has asked to join an interest group, so that its contacts are
+// only told once.
+type JoinRequest struct {
+	gorm.Model
+	GroupID uint
+	UserID  int
+}
+
+// InterestGroups returns all the interest groups, ordered by name.
+func (db *Database) InterestGroups() []InterestGroup {
+	var groups []InterestGroup
+	db.Order("name").Find(&groups)
+	return groups
+}
+
+// InterestGroup returns the interest group with the given id.
+func (db *Database) InterestGroup(id uint) (InterestGroup, error) {
+	var group InterestGroup
+	err := db.Where("id = ?", id).First(&group).Error
+	return group, err
+}
+
+// findInterestGroup looks up an interest group by name, ignoring case.
+func findInterestGroup(tx *gorm.DB, name string) (InterestGroup, error) {
+	var group InterestGroup
+	err := tx.Where("LOWER(name) = LOWER(?)", name).First(&group).Error
+	return group, err
+}
+
+// AddInterestGroup adds an interest group and records the change.
+func (db *Database) AddInterestGroup(adminID int, group InterestGroup) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		_, err := findInterestGroup(tx, group.Name)
+		if err == nil {
+			return ErrInterestGroupExists
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ResourceChange{AdminID: adminID, Action: "add", Category: "groups", Name: group.Name, NewValue: group.Contacts}).Error
+	})
+}
+
+// EditInterestGroup changes one of the Fields of an interest group and records the change.
+func (db *Database) EditInterestGroup(adminID int, name, field, value string) error {
+	if _, ok := (InterestGroup{}).Fields()[field]; !ok {
+		return errors.New("unknown interest group field " + field)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		group, err := findInterestGroup(tx, name)
+		if err != nil {
+			return err
+		}
+		old := group.Fields()[field]
+		if err := tx.Model(&group).Update(field, value).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ResourceChange{AdminID: adminID, Action: "edit", Category: "groups", Name: group.Name + " (" + field + ")", OldValue: old, NewValue: value}).Error
+	})
+}
+
+// DeleteInterestGroup deletes an interest group and its join requests, and records the change.
+func (db *Database) DeleteInterestGroup(adminID int, name string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		group, err := findInterestGroup(tx, name)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&group).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("group_id = ?", group.ID).Delete(&JoinRequest{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ResourceChange{AdminID: adminID, Action: "remove", Category: "groups", Name: group.Name, OldValue: group.Contacts}).Error
+	})
+}
+
+// AddJoinRequest records that the user has asked to join the group. It returns false if they had
+// already asked.
+func (db *Database) AddJoinRequest(groupID uint, userID int) (bool, error) {
+	var existing JoinRequest
+	err := db.Where("group_id = ? AND user_id = ?", groupID, userID).First(&existing).Error
+	if err == nil {
+		return false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+	return true, db.Create(&JoinRequest{GroupID: groupID, UserID: userID}).Error
+}
+
+// DeleteJoinRequest removes the record that the user has asked to join the group, so that they can
+// ask again.
+func (db *Database) DeleteJoinRequest(groupID uint, userID int) error {
+	return db.Unscoped().Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&JoinRequest{}).Error
+}
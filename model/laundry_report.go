@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// LaundryReport is a fault report filed by a resident against a specific laundry machine.
+// A machine stays flagged as faulty until an admin resolves all open reports against it.
+type LaundryReport struct {
+	gorm.Model
+	UserID     int
+	Level      int
+	Machine    string
+	Ezlink     bool
+	Fault      string
+	PhotoID    string // Telegram file_id of the attached photo, if any
+	Resolved   bool
+	ResolvedBy int
+	ResolvedAt *time.Time
+}
+
+// OpenLaundryReports returns all laundry reports which have not been resolved, oldest first.
+func (db *Database) OpenLaundryReports() []LaundryReport {
+	var reports []LaundryReport
+	db.Where("resolved = ?", false).Order("created_at").Find(&reports)
+	return reports
+}
+
+// AttachLaundryReportPhoto sets the photo of the report with the given id.
+func (db *Database) AttachLaundryReportPhoto(id uint, fileID string) error {
+	return db.Model(&LaundryReport{}).Where("id = ?", id).Update("photo_id", fileID).Error
+}
+
+// ResolveLaundryReport marks the report with the given id as resolved by the admin.
+func (db *Database) ResolveLaundryReport(id uint, adminID int) error {
+	now := time.Now()
+	result := db.Model(&LaundryReport{}).Where("id = ? AND resolved = ?", id, false).Updates(map[string]interface{}{
+		"resolved":    true,
+		"resolved_by": adminID,
+		"resolved_at": &now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AddLaundryReport files the report, setting its ID.
+func (db *Database) AddLaundryReport(report *LaundryReport) error {
+	return db.Create(report).Error
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Place is a location saved by a user under a name, eg. "home".
+type Place struct {
+	gorm.Model
+	UserID    int
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+// Places returns the places saved by the user, in the order they were saved.
+func (db *Database) Places(userID int) []Place {
+	var places []Place
+	db.Where("user_id = ?", userID).Order("created_at").Find(&places)
+	return places
+}
+
+// SavePlace saves a place for the user, moving it if the user already has a place with that name.
+func (db *Database) SavePlace(userID int, name string, lat, lng float64) error {
+	var existing Place
+	err := db.Where("user_id = ? AND name = ?", userID, name).First(&existing).Error
+	if err == nil {
+		return db.Model(&existing).Updates(map[string]interface{}{"latitude": lat, "longitude": lng}).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return db.Create(&Place{UserID: userID, Name: name, Latitude: lat, Longitude: lng}).Error
+}
+
+// DeletePlace deletes the user's place with the given name.
+func (db *Database) DeletePlace(userID int, name string) error {
+	result := db.Unscoped().Where("user_id = ? AND name = ?", userID, name).Delete(&Place{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrResourceExists is returned when adding a resource with the same category and name as another.
+var ErrResourceExists = errors.New("resource already exists")
+
+// Resource is an entry in the /resources directory, eg. a Telegram channel or the contact for an
+// interest group. Value is shown as Markdown.
+type Resource struct {
+	gorm.Model
+	Category string
+	Name     string
+	Value    string
+}
+
+// ResourceChange records a change to the resources by an admin. AdminID is 0 for changes made by
+// the bot itself, eg. loading the resources for the first time.
+type ResourceChange struct {
+	gorm.Model
+	AdminID  int
+	Action   string // "add", "edit", "remove" or "import"
+	Category string
+	Name     string
+	OldValue string
+	NewValue string
+}
+
+// Resources returns the resources in a category, or in every category if it is empty, ordered by
+// category and name.
+func (db *Database) Resources(category string) []Resource {
+	var resources []Resource
+	query := db.Order("category").Order("name")
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	query.Find(&resources)
+	return resources
+}
+
+// findResource looks up a resource by category and name, ignoring the case of the name.
+func findResource(tx *gorm.DB, category, name string) (Resource, error) {
+	var r Resource
+	err := tx.Where("category = ? AND LOWER(name) = LOWER(?)", category, name).First(&r).Error
+	return r, err
+}
+
+// AddResource adds a resource and records the change.
+func (db *Database) AddResource(adminID int, category, name, value string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		_, err := findResource(tx, category, name)
+		if err == nil {
+			return ErrResourceExists
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err := tx.Create(&Resource{Category: category, Name: name, Value: value}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ResourceChange{AdminID: adminID, Action: "add", Category: category, Name: name, NewValue: value}).Error
+	})
+}
+
+// EditResource changes the value of a resource and records the change.
+func (db *Database) EditResource(adminID int, category, name, value string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		r, err := findResource(tx, category, name)
+		if err != nil {
+			return err
+		}
+		old := r.Value
+		if err := tx.Model(&r).Update("value", value).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ResourceChange{AdminID: adminID, Action: "edit", Category: category, Name: r.Name, OldValue: old, NewValue: value}).Error
+	})
+}
+
+// DeleteResource deletes a resource and records the change.
+func (db *Database) DeleteResource(adminID int, category, name string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		r, err := findResource(tx, category, name)
+		if err != nil {
+			return err
+		}
+		// the change keeps the old value, so the resource itself is not kept
+		if err := tx.Unscoped().Delete(&r).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ResourceChange{AdminID: adminID, Action: "remove", Category: category, Name: r.Name, OldValue: r.Value}).Error
+	})
+}
+
+// ReplaceResources replaces all the resources, eg. with those imported from a file, and records
+// the change.
+func (db *Database) ReplaceResources(adminID int, resources []Resource) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&Resource{}).Error; err != nil {
+			return err
+		}
+		for _, r := range resources {
+			if err := tx.Create(&Resource{Category: r.Category, Name: r.Name, Value: r.Value}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&ResourceChange{AdminID: adminID, Action: "import"}).Error
+	})
+}
+
+// ResourceChanges returns the latest changes to the resources, newest first.
+func (db *Database) ResourceChanges(limit int) []ResourceChange {
+	var changes []ResourceChange
+	db.Order("created_at desc").Order("id desc").Limit(limit).Find(&changes)
+	return changes
+}
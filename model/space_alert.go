@@ -0,0 +1,103 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// VenueWatch is a request by a user to be told about new, changed and cancelled bookings at a venue.
+type VenueWatch struct {
+	gorm.Model
+	UserID int
+	Venue  string
+}
+
+// EventReminder is a request by a user to be reminded before a booking starts. The booking is
+// copied so that the reminder can be sent without looking it up, and is updated when it changes.
+type EventReminder struct {
+	gorm.Model
+	UserID  int
+	EventID string // name of the Firestore document of the booking
+	Name    string
+	Venue   string
+	Start   time.Time
+	Before  int // minutes before the start to remind at
+}
+
+// RemindAt returns when the reminder should be sent.
+func (r EventReminder) RemindAt() time.Time {
+	return r.Start.Add(-time.Duration(r.Before) * time.Minute)
+}
+
+// VenueWatches returns the venue watches of every user.
+func (db *Database) VenueWatches() []VenueWatch {
+	var watches []VenueWatch
+	db.Order("user_id").Find(&watches)
+	return watches
+}
+
+// UserVenueWatches returns the venues watched by the user, in the order they were watched.
+func (db *Database) UserVenueWatches(userID int) []VenueWatch {
+	var watches []VenueWatch
+	db.Where("user_id = ?", userID).Order("created_at").Find(&watches)
+	return watches
+}
+
+// ToggleVenueWatch watches the venue for the user if they are not watching it, and stops watching
+// it otherwise. Returns true if the venue is now watched.
+func (db *Database) ToggleVenueWatch(userID int, venue string) (bool, error) {
+	var existing VenueWatch
+	err := db.Where("user_id = ? AND venue = ?", userID, venue).First(&existing).Error
+	if err == nil {
+		return false, db.Unscoped().Delete(&existing).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+	return true, db.Create(&VenueWatch{UserID: userID, Venue: venue}).Error
+}
+
+// EventReminders returns the reminders of every user, ordered by when they should be sent.
+func (db *Database) EventReminders() []EventReminder {
+	var reminders []EventReminder
+	db.Order("start").Find(&reminders)
+	return reminders
+}
+
+// UserEventReminders returns the user's reminders, ordered by the start of their bookings.
+func (db *Database) UserEventReminders(userID int) []EventReminder {
+	var reminders []EventReminder
+	db.Where("user_id = ?", userID).Order("start").Find(&reminders)
+	return reminders
+}
+
+// ToggleEventReminder adds the reminder if the user has none for its booking, and removes it
+// otherwise. Returns true if the user now has a reminder.
+func (db *Database) ToggleEventReminder(reminder EventReminder) (bool, error) {
+	var existing EventReminder
+	err := db.Where("user_id = ? AND event_id = ?", reminder.UserID, reminder.EventID).First(&existing).Error
+	if err == nil {
+		return false, db.Unscoped().Delete(&existing).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+	return true, db.Create(&reminder).Error
+}
+
+// UpdateEventReminders updates the booking copied into every reminder for it.
+func (db *Database) UpdateEventReminders(eventID, name, venue string, start time.Time) error {
+	return db.Model(&EventReminder{}).Where("event_id = ?", eventID).
+		Updates(map[string]interface{}{"name": name, "venue": venue, "start": start}).Error
+}
+
+// DeleteEventReminder removes a reminder, eg. once it has been sent.
+func (db *Database) DeleteEventReminder(id uint) error {
+	return db.Unscoped().Where("id = ?", id).Delete(&EventReminder{}).Error
+}
+
+// DeleteEventReminders removes every reminder for a booking, eg. when it is cancelled.
+func (db *Database) DeleteEventReminders(eventID string) error {
+	return db.Unscoped().Where("event_id = ?", eventID).Delete(&EventReminder{}).Error
+}
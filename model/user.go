@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -47,3 +48,18 @@ func (db *Database) UserGroup(tags []string) []User {
 
 	return users
 }
+
+// UsersByUserName returns the users with the given Telegram usernames, ignoring case. Only users
+// who have messaged the bot are known.
+func (db *Database) UsersByUserName(names []string) []User {
+	if len(names) == 0 {
+		return nil
+	}
+	lower := make([]string, len(names))
+	for i, name := range names {
+		lower[i] = strings.ToLower(name)
+	}
+	var users []User
+	db.Where("LOWER(user_name) IN (?)", lower).Find(&users)
+	return users
+}
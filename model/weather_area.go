@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+)
+
+// WeatherArea is the area of the 2h weather forecast which a user gets rain alerts for.
+type WeatherArea struct {
+	UserID    int `gorm:"primary_key;auto_increment:false"`
+	UpdatedAt time.Time
+	Area      string // eg. "Queenstown"
+}
+
+// SavedWeatherArea returns the area the user gets rain alerts for, or "" if they have not chosen one.
+func (db *Database) SavedWeatherArea(userID int) string {
+	var area WeatherArea
+	db.Where("user_id = ?", userID).First(&area)
+	return area.Area
+}
+
+// SaveWeatherArea sets the area the user gets rain alerts for.
+func (db *Database) SaveWeatherArea(userID int, area string) error {
+	return db.Save(&WeatherArea{UserID: userID, Area: area}).Error
+}
@@ -0,0 +1,149 @@
+package cinnabot
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usdevs/cinnabot/model"
+	"github.com/usdevs/cinnabot/transit"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// Kinds of bus stops which can be starred
+const (
+	nusStop    = "nus"
+	publicStop = "public"
+)
+
+// busClient returns the transit client for the kind of stop
+func (cb *Cinnabot) busClient(kind string) *transit.Client {
+	if kind == publicStop {
+		return cb.publicBus
+	}
+	return cb.nusBus
+}
+
+// makeStarButtons returns rows of buttons to star each of the stops, 2 per row
+func makeStarButtons(kind string, stops []transit.Stop) [][]tgbotapi.InlineKeyboardButton {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0)
+	var row []tgbotapi.InlineKeyboardButton
+	for _, stop := range stops {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("⭐ "+stop.Name, "//bus_star "+kind+" "+stop.Code))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// stopName looks up the name of a stop, defaulting to the code
+func stopName(client *transit.Client, code string) string {
+	if stop, ok := client.Stop(code); ok {
+		return stop.Name
+	}
+	return code
+}
+
+// myBusResponse returns the bus timings at all the favourite stops
+func myBusResponse(nusClient, publicClient *transit.Client, favourites []model.FavouriteStop, now time.Time) string {
+	nusCodes := make([]string, 0)
+	publicCodes := make([]string, 0)
+	for _, fav := range favourites {
+		if fav.Kind == publicStop {
+			publicCodes = append(publicCodes, fav.Code)
+		} else {
+			nusCodes = append(nusCodes, fav.Code)
+		}
+	}
+
+	// Fetch NUS and public bus timings concurrently
+	var nusResults, publicResults []transit.StopArrivals
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		nusResults = nusClient.ArrivalsAt(context.Background(), nusCodes)
+	}()
+	go func() {
+		defer wg.Done()
+		publicResults = publicClient.ArrivalsAt(context.Background(), publicCodes)
+	}()
+	wg.Wait()
+
+	lines := []string{"🤖: Here are the bus timings at your favourite stops"}
+	for _, fav := range favourites {
+		if fav.Kind == publicStop {
+			lines = append(lines, formatPublicBusTimings(fav.Name, "", publicResults[0], now))
+			publicResults = publicResults[1:]
+		} else {
+			lines = append(lines, formatNUSBusTimings(fav.Name, "", nusResults[0], now))
+			nusResults = nusResults[1:]
+		}
+	}
+	if len(publicCodes) > 0 {
+		lines = append(lines, publicBusLegend)
+	}
+	lines = append(lines, "Last updated: "+now.Format(time.RFC822))
+	return strings.Join(lines, "\n")
+}
+
+func makeMyBusKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		makeRefreshRow("//mybus_refresh"),
+	)
+}
+
+const noFavouriteStopsStr = "🤖: You have no favourite bus stops yet.\n\n" +
+	"Use /nusbus or /publicbus and tap ⭐ under a bus stop to add it to your favourites."
+
+// MyBus shows the bus timings at all of the user's favourite stops
+func (cb *Cinnabot) MyBus(msg *message) {
+	favourites := cb.db.FavouriteStops(msg.From.ID)
+	if len(favourites) == 0 {
+		cb.SendTextMessage(int(msg.Chat.ID), noFavouriteStopsStr)
+		return
+	}
+	responseString := myBusResponse(cb.nusBus, cb.publicBus, favourites, time.Now())
+	cb.SendMessage(NewMessageWithButton(responseString, makeMyBusKeyboard(), msg.Chat.ID))
+}
+
+// MyBusRefresh handles the refresh button for messages from /mybus
+func (cb *Cinnabot) MyBusRefresh(qry *Callback) {
+	favourites := cb.db.FavouriteStops(qry.From.ID)
+	if len(favourites) == 0 {
+		cb.SendMessage(EditedMessage(noFavouriteStopsStr, qry.ChatID, qry.MsgID))
+		return
+	}
+	responseString := myBusResponse(cb.nusBus, cb.publicBus, favourites, time.Now())
+	cb.SendMessage(EditedMessageWithButton(responseString, makeMyBusKeyboard(), qry.ChatID, qry.MsgID))
+}
+
+// BusStar handles the star buttons on bus timing messages, adding or removing the stop from the user's favourites
+func (cb *Cinnabot) BusStar(qry *Callback) {
+	if len(qry.Args) < 2 {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while starring the bus stop")
+		return
+	}
+	kind := qry.Args[0]
+	code := strings.Join(qry.Args[1:], " ")
+	name := stopName(cb.busClient(kind), code)
+
+	starred, err := cb.db.ToggleFavouriteStop(qry.From.ID, kind, code, name)
+	if err != nil {
+		log.Print(err)
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while starring the bus stop")
+		return
+	}
+	if starred {
+		cb.SendTextMessage(int(qry.ChatID), "🤖: Added *"+name+"* to your favourites. Use /mybus to see timings for all your favourite stops.")
+	} else {
+		cb.SendTextMessage(int(qry.ChatID), "🤖: Removed *"+name+"* from your favourites.")
+	}
+}
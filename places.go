@@ -0,0 +1,182 @@
+package cinnabot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cache "github.com/patrickmn/go-cache"
+	"github.com/usdevs/cinnabot/model"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// placePrefix starts the text of the keyboard buttons for saved places, eg. "📍 home", so that
+// location-aware commands can tell them apart from other replies
+const placePrefix = "📍"
+
+const (
+	maxPlacesPerUser = 6
+	maxPlaceNameLen  = 20 // keeps names short enough for buttons and callback data
+)
+
+// placeNamePattern matches the names places can be saved under, which are shown in Markdown messages
+var placeNamePattern = regexp.MustCompile(`^[a-z0-9' -]+$`)
+
+// placeKey is the key in the cache of the name of the place a user is adding
+func placeKey(userID int) string {
+	return "place_" + strconv.Itoa(userID)
+}
+
+// normalisePlaceName turns the words of a place name into the name it is saved as
+func normalisePlaceName(words []string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.Join(words, " ")), " "))
+}
+
+// placeRows returns keyboard rows with a button for each place, two to a row
+func placeRows(places []model.Place) [][]tgbotapi.KeyboardButton {
+	rows := make([][]tgbotapi.KeyboardButton, 0)
+	for i := 0; i < len(places); i += 2 {
+		row := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(placePrefix + " " + places[i].Name))
+		if i+1 < len(places) {
+			row = append(row, tgbotapi.NewKeyboardButton(placePrefix+" "+places[i+1].Name))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// locationKeyboard returns a reply keyboard with the given rows, followed by the user's saved places
+func (cb *Cinnabot) locationKeyboard(userID int, rows ...[]tgbotapi.KeyboardButton) tgbotapi.ReplyKeyboardMarkup {
+	return tgbotapi.NewReplyKeyboard(append(rows, placeRows(cb.db.Places(userID))...)...)
+}
+
+// findPlace returns the place named by args, if they are the text of a saved place's button
+func findPlace(places []model.Place, args []string) (model.Place, bool) {
+	if len(args) < 2 || args[0] != placePrefix {
+		return model.Place{}, false
+	}
+	name := normalisePlaceName(args[1:])
+	for _, p := range places {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return model.Place{}, false
+}
+
+// useSavedPlace replaces the text of a saved place's button in msg with the place's location, as if
+// the user had shared it. If the place no longer exists, the arguments are cleared so that the user
+// is asked where they are again.
+func (cb *Cinnabot) useSavedPlace(msg *message) {
+	if len(msg.Args) == 0 || msg.Args[0] != placePrefix {
+		return
+	}
+	place, ok := findPlace(cb.db.Places(msg.From.ID), msg.Args)
+	if !ok {
+		msg.Args = nil
+		return
+	}
+	msg.Location = &tgbotapi.Location{Latitude: place.Latitude, Longitude: place.Longitude}
+	msg.Args = []string{""}
+}
+
+// placesList describes the user's places, with a button to delete each
+func placesList(places []model.Place) (string, tgbotapi.InlineKeyboardMarkup) {
+	text := "🤖: Save places like home or your lab, and I'll show them as buttons whenever I ask where you are.\n\n" +
+		"To save a place, send /places add <name>, eg. /places add lab, then send me its location."
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(places) > 0 {
+		text = "🤖: Your places:\n"
+		for _, p := range places {
+			text += placePrefix + " " + p.Name + "\n"
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🗑 Delete "+p.Name, "//place_del "+p.Name)))
+		}
+		text += "\nTo save another place, send /places add <name>, then send me its location."
+	}
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// sendPlaces sends the list of the user's places
+func (cb *Cinnabot) sendPlaces(chatID int64, userID int) {
+	text, keyboard := placesList(cb.db.Places(userID))
+	if len(keyboard.InlineKeyboard) == 0 {
+		cb.SendTextMessage(int(chatID), text)
+		return
+	}
+	cb.SendMessage(NewMessageWithButton(text, keyboard, chatID))
+}
+
+// Places lists the user's saved places. /places add <name> saves the location sent next under
+// the name, and /places delete <name> deletes a place.
+func (cb *Cinnabot) Places(msg *message) {
+	userID := msg.From.ID
+
+	// Location for a place being added
+	if msg.Location != nil {
+		name, ok := cb.cache.Get(placeKey(userID))
+		if !ok {
+			cb.SendTextMessage(int(msg.Chat.ID), "🤖: Which place is this? Send /places add <name> first.")
+			return
+		}
+		cb.cache.Delete(placeKey(userID))
+		if err := cb.db.SavePlace(userID, name.(string), msg.Location.Latitude, msg.Location.Longitude); err != nil {
+			cb.log.Printf("cannot save place: %s", err)
+			cb.SendTextMessage(int(msg.Chat.ID), "🤖: Sorry, I couldn't save your place. Please try again later.")
+			return
+		}
+		cb.SendTextMessage(int(msg.Chat.ID), fmt.Sprintf("🤖: Saved %s %s! It'll show up whenever I ask where you are.", placePrefix, name))
+		return
+	}
+
+	if len(msg.Args) == 0 || msg.Args[0] == "" {
+		cb.sendPlaces(msg.Chat.ID, userID)
+		return
+	}
+
+	name := normalisePlaceName(msg.Args[1:])
+	switch strings.ToLower(msg.Args[0]) {
+	case "add":
+		if len(name) > maxPlaceNameLen || !placeNamePattern.MatchString(name) {
+			cb.SendTextMessage(int(msg.Chat.ID), fmt.Sprintf("🤖: Give your place a name of up to %d letters and numbers, eg. /places add home", maxPlaceNameLen))
+			return
+		}
+		places := cb.db.Places(userID)
+		if _, exists := findPlace(places, []string{placePrefix, name}); !exists && len(places) >= maxPlacesPerUser {
+			cb.SendTextMessage(int(msg.Chat.ID), fmt.Sprintf("🤖: You can only save %d places. Delete one with /places first.", maxPlacesPerUser))
+			return
+		}
+		cb.cache.Set(placeKey(userID), name, cache.DefaultExpiration)
+
+		here := tgbotapi.NewKeyboardButton("Here")
+		here.RequestLocation = true
+		replyMsg := tgbotapi.NewMessage(msg.Chat.ID, "🤖: Where is "+name+"? Tap Here if you're there now, or attach a location with 📎")
+		replyMsg.ReplyMarkup = tgbotapi.NewReplyKeyboard(tgbotapi.NewKeyboardButtonRow(here))
+		cb.SendMessage(replyMsg)
+	case "delete":
+		if err := cb.db.DeletePlace(userID, name); err != nil {
+			cb.SendTextMessage(int(msg.Chat.ID), "🤖: You don't have a place called "+name+".")
+			return
+		}
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Deleted "+placePrefix+" "+name+".")
+	default:
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Send /places add <name> to save a place, or /places to see your places.")
+	}
+}
+
+// PlaceDelete deletes the place in the callback
+func (cb *Cinnabot) PlaceDelete(qry *Callback) {
+	name := normalisePlaceName(qry.Args)
+	if name == "" {
+		return
+	}
+	if err := cb.db.DeletePlace(qry.From.ID, name); err != nil {
+		cb.log.Printf("cannot delete place: %s", err)
+	}
+	text, keyboard := placesList(cb.db.Places(qry.From.ID))
+	if len(keyboard.InlineKeyboard) == 0 {
+		cb.SendMessage(EditedMessage(text, qry.ChatID, qry.MsgID))
+		return
+	}
+	cb.SendMessage(EditedMessageWithButton(text, keyboard, qry.ChatID, qry.MsgID))
+}
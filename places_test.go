@@ -0,0 +1,58 @@
+package cinnabot
+
+import (
+	"testing"
+
+	"github.com/usdevs/cinnabot/model"
+)
+
+func TestPlaceRows(t *testing.T) {
+	places := []model.Place{{Name: "home"}, {Name: "lab"}, {Name: "hall"}}
+	rows := placeRows(places)
+	if len(rows) != 2 || len(rows[0]) != 2 || len(rows[1]) != 1 {
+		t.Fatalf("expected rows of 2 and 1 buttons, got %v", rows)
+	}
+	if rows[0][1].Text != "📍 lab" {
+		t.Errorf("expected button text %q, got %q", "📍 lab", rows[0][1].Text)
+	}
+	if len(placeRows(nil)) != 0 {
+		t.Error("expected no rows without places")
+	}
+}
+
+func TestFindPlace(t *testing.T) {
+	places := []model.Place{{Name: "home", Latitude: 1.3}, {Name: "com2 lab", Latitude: 1.29}}
+	tests := []struct {
+		args     []string
+		expected string
+		ok       bool
+	}{
+		{[]string{"📍", "home"}, "home", true},
+		{[]string{"📍", "COM2", "Lab"}, "com2 lab", true},
+		{[]string{"📍", "gym"}, "", false},
+		{[]string{"home"}, "", false},
+		{[]string{"📍"}, "", false},
+	}
+	for _, test := range tests {
+		place, ok := findPlace(places, test.args)
+		if ok != test.ok || place.Name != test.expected {
+			t.Errorf("findPlace(%v): expected %q %v, got %q %v", test.args, test.expected, test.ok, place.Name, ok)
+		}
+	}
+}
+
+func TestPlaceNames(t *testing.T) {
+	if got := normalisePlaceName([]string{" My", "  Lab "}); got != "my lab" {
+		t.Errorf("expected %q, got %q", "my lab", got)
+	}
+	for _, name := range []string{"home", "com2 lab", "mum's place"} {
+		if !placeNamePattern.MatchString(name) {
+			t.Errorf("expected %q to be a valid name", name)
+		}
+	}
+	for _, name := range []string{"", "*home*", "my_lab"} {
+		if placeNamePattern.MatchString(name) {
+			t.Errorf("expected %q to be an invalid name", name)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package cinnabot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usdevs/cinnabot/utils"
+	"github.com/usdevs/cinnabot/weather"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// RainAlertInterval is how often CheckRainAlerts should be run. The 2h forecast is updated
+// about every half hour, and is cached by the weather client in between.
+const RainAlertInterval = 5 * time.Minute
+
+// rainAlertCooldown is the shortest time between two rain alerts to the same user, so that
+// forecasts which flip between fair and showers don't spam them
+const rainAlertCooldown = 3 * time.Hour
+
+// defaultRainArea is used for subscribers who have not chosen an area. Cinnamon is in Queenstown.
+const defaultRainArea = "Queenstown"
+
+// wetForecasts are the words in forecasts of rain, eg. "Thundery Showers" or "Light Rain"
+var wetForecasts = []string{"rain", "shower", "thunder", "drizzle"}
+
+// isWet checks if a forecast is for rain
+func isWet(forecast string) bool {
+	forecast = strings.ToLower(forecast)
+	for _, w := range wetForecasts {
+		if strings.Contains(forecast, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// rainAlerts remembers whether rain was forecast for each area at the last check, and when each
+// user was last alerted
+type rainAlerts struct {
+	mu      sync.Mutex
+	wet     map[string]bool
+	alerted map[int]time.Time
+}
+
+func newRainAlerts() *rainAlerts {
+	return &rainAlerts{wet: make(map[string]bool), alerted: make(map[int]time.Time)}
+}
+
+// due records the latest forecasts and returns the subscribers to alert, in order of user ID.
+// subscribers maps user IDs to their areas. Users are alerted when the forecast for their area
+// changes from fair to rain, unless they were alerted recently.
+func (ra *rainAlerts) due(subscribers map[int]string, forecasts map[string]string, now time.Time) []int {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	started := make(map[string]bool)
+	for area, forecast := range forecasts {
+		wasWet, known := ra.wet[area]
+		ra.wet[area] = isWet(forecast)
+		started[area] = known && !wasWet && ra.wet[area]
+	}
+
+	users := make([]int, 0)
+	for userID, area := range subscribers {
+		if !started[area] || now.Sub(ra.alerted[userID]) < rainAlertCooldown {
+			continue
+		}
+		ra.alerted[userID] = now
+		users = append(users, userID)
+	}
+	sort.Ints(users)
+	return users
+}
+
+// CheckRainAlerts messages subscribers of the weather tag when rain is forecast for their area,
+// as chosen with /rainalerts.
+func (cb *Cinnabot) CheckRainAlerts() {
+	ctx, cancel := context.WithTimeout(context.Background(), weatherRequestTime)
+	defer cancel()
+	forecast, err := cb.weather.TwoHour(ctx)
+	if err != nil {
+		cb.log.Printf("cannot check rain alerts: %s", err)
+		return
+	}
+
+	subscribers := make(map[int]string)
+	for _, user := range cb.db.UserGroup([]string{"weather"}) {
+		area := cb.db.SavedWeatherArea(user.UserID)
+		if area == "" {
+			area = defaultRainArea
+		}
+		subscribers[user.UserID] = area
+	}
+
+	for _, userID := range cb.rainAlerts.due(subscribers, forecast.Forecasts, time.Now()) {
+		area := subscribers[userID]
+		text := fmt.Sprintf("☔ Rain is on the way! The 2h forecast for *%s* is now %s, until %s.\n\nBetter bring an umbrella 🌂",
+			area, describeForecast(forecast.Forecasts[area]), forecast.ValidTo.Format("3pm"))
+		cb.SendTextMessage(userID, text)
+	}
+}
+
+// rainAlertsStatus describes the user's rain alerts, with a button to turn them on or off
+func rainAlertsStatus(subscribed bool, area string) (string, tgbotapi.InlineKeyboardMarkup) {
+	var text string
+	var button tgbotapi.InlineKeyboardButton
+	if subscribed {
+		text = "🤖: Rain alerts are *on* for " + area + ". I'll tell you when the 2h forecast there changes to rain.\n\n"
+		button = tgbotapi.NewInlineKeyboardButtonData("🔕 Turn off", "//rain_sub off")
+	} else {
+		text = "🤖: Rain alerts are *off*. Turn them on and I'll tell you when the 2h forecast for " + area + " changes to rain.\n\n"
+		button = tgbotapi.NewInlineKeyboardButtonData("🔔 Turn on", "//rain_sub on")
+	}
+	text += "To change the area, send /rainalerts <area>, eg. /rainalerts clementi, or share your location after /rainalerts."
+	return text, tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(button))
+}
+
+// rainArea returns the area the user gets rain alerts for
+func (cb *Cinnabot) rainArea(userID int) string {
+	if area := cb.db.SavedWeatherArea(userID); area != "" {
+		return area
+	}
+	return defaultRainArea
+}
+
+// findArea returns the forecast area which best matches query
+func findArea(areas []weather.Area, query string) (string, bool) {
+	names := make([]string, len(areas))
+	for i, a := range areas {
+		names[i] = a.Name
+	}
+	matches := utils.FuzzyRank(query, names)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return names[matches[0]], true
+}
+
+// RainAlerts shows whether the user gets rain alerts. Sending an area or a location sets the area
+// they are for, and turns them on.
+func (cb *Cinnabot) RainAlerts(msg *message) {
+	cb.useSavedPlace(msg)
+	userID := msg.From.ID
+	query := strings.TrimSpace(msg.GetArgString())
+
+	if msg.Location != nil || query != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), weatherRequestTime)
+		defer cancel()
+		forecast, err := cb.weather.TwoHour(ctx)
+		if err != nil {
+			cb.log.Printf("cannot get forecast areas: %s", err)
+			cb.SendTextMessage(int(msg.Chat.ID), weatherErrorStr(err))
+			return
+		}
+
+		var area string
+		var ok bool
+		if msg.Location != nil {
+			var nearest weather.Area
+			nearest, _, ok = forecast.Nearest(msg.Location.Latitude, msg.Location.Longitude)
+			area = nearest.Name
+		} else {
+			area, ok = findArea(forecast.Areas, query)
+		}
+		if !ok {
+			cb.SendTextMessage(int(msg.Chat.ID), "🤖: I don't know where that is. Try an area like Clementi or Queenstown.")
+			return
+		}
+
+		if err := cb.db.SaveWeatherArea(userID, area); err != nil {
+			cb.log.Printf("cannot save weather area: %s", err)
+		}
+		if err := cb.db.UpdateTag(userID, "weather", "true"); err != nil {
+			cb.log.Printf("cannot subscribe to rain alerts: %s", err)
+		}
+	}
+
+	text, keyboard := rainAlertsStatus(cb.db.CheckSubscribed(userID, "weather"), cb.rainArea(userID))
+	cb.SendMessage(NewMessageWithButton(text, keyboard, msg.Chat.ID))
+}
+
+// RainAlertSubscribe turns rain alerts on or off
+func (cb *Cinnabot) RainAlertSubscribe(qry *Callback) {
+	if len(qry.Args) < 1 {
+		return
+	}
+	userID := qry.From.ID
+	flag := "false"
+	if qry.Args[0] == "on" {
+		flag = "true"
+	}
+	if err := cb.db.UpdateTag(userID, "weather", flag); err != nil {
+		cb.log.Printf("cannot update rain alerts: %s", err)
+	}
+
+	text, keyboard := rainAlertsStatus(cb.db.CheckSubscribed(userID, "weather"), cb.rainArea(userID))
+	cb.SendMessage(EditedMessageWithButton(text, keyboard, qry.ChatID, qry.MsgID))
+}
@@ -0,0 +1,547 @@
+package cinnabot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	cache "github.com/patrickmn/go-cache"
+	"github.com/usdevs/cinnabot/model"
+	"github.com/usdevs/cinnabot/utils"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+const (
+	maxResourcesFileSize = 1 << 20 // bytes, imported files larger than this are rejected
+	resourceChangesShown = 10
+)
+
+// resourceCategory is a category of /resources
+type resourceCategory struct {
+	Key  string // used in commands, and stored in the database
+	JSON string // key in resources.json
+}
+
+var resourceCategories = []resourceCategory{
+	{"telegram", "telegram"},
+	{"links", "links"},
+}
+
+// findResourceCategory returns the category with the given key, ignoring case
+func findResourceCategory(key string) (resourceCategory, bool) {
+	for _, c := range resourceCategories {
+		if strings.EqualFold(c.Key, key) {
+			return c, true
+		}
+	}
+	return resourceCategory{}, false
+}
+
+// resourceImportKey is the key in the cache which is set while an admin is importing resources
+func resourceImportKey(userID int) string {
+	return "resource_import_" + strconv.Itoa(userID)
+}
+
+// parseResources parses resources in the format of resources.json, which maps the JSON key of
+// each category to the names and values of its resources
+func parseResources(data []byte) ([]model.Resource, error) {
+	var raw map[string]map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	resources := make([]model.Resource, 0)
+	for _, c := range resourceCategories {
+		names := make([]string, 0, len(raw[c.JSON]))
+		for name := range raw[c.JSON] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			resources = append(resources, model.Resource{Category: c.Key, Name: name, Value: raw[c.JSON][name]})
+		}
+		delete(raw, c.JSON)
+	}
+	// Interest groups are kept separately, see parseInterestGroups
+	delete(raw, interestGroupsJSON)
+	for key := range raw {
+		return nil, fmt.Errorf("unknown category %q", key)
+	}
+	return resources, nil
+}
+
+// formatResourcesJSON formats resources in the format of resources.json
+func formatResourcesJSON(resources []model.Resource) ([]byte, error) {
+	raw := make(map[string]map[string]string)
+	for _, c := range resourceCategories {
+		raw[c.JSON] = make(map[string]string)
+	}
+	for _, r := range resources {
+		c, ok := findResourceCategory(r.Category)
+		if !ok {
+			continue
+		}
+		raw[c.JSON][r.Name] = r.Value
+	}
+	return json.MarshalIndent(raw, "", "    ")
+}
+
+// loadResources fills the resources from a file in the format of resources.json, if there are none
+// yet. The file is only used the first time the bot is run; after that admins edit the resources
+// with /editresources.
+func (cb *Cinnabot) loadResources(path string) error {
+	if len(cb.db.Resources("")) > 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	resources, err := parseResources(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return cb.db.ReplaceResources(0, resources)
+}
+
+// formatResource formats a resource for a list
+func formatResource(r model.Resource) string {
+	return r.Name + " : " + r.Value
+}
+
+// resourcesPerPage is how many resources are shown at once, so that long categories are split
+// into pages
+const resourcesPerPage = 15
+
+// weakResourceMatch is the highest score utils.FuzzyScore gives when the query's letters only
+// appear in order. Such matches are noise across a whole directory, so they are only shown if there
+// are no better ones.
+const weakResourceMatch = 10
+
+// maxResourceResults is the most search results shown
+const maxResourceResults = 10
+
+// categoryIndex returns the position of a category in resourceCategories, for sorting
+func categoryIndex(key string) int {
+	for i, c := range resourceCategories {
+		if c.Key == key {
+			return i
+		}
+	}
+	return len(resourceCategories)
+}
+
+// sortResources sorts resources in the order of their categories, then by name
+func sortResources(resources []model.Resource) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		ci, cj := categoryIndex(resources[i].Category), categoryIndex(resources[j].Category)
+		if ci != cj {
+			return ci < cj
+		}
+		return resources[i].Name < resources[j].Name
+	})
+}
+
+// formatResources lists resources under a heading for each category
+func formatResources(resources []model.Resource) string {
+	var lines []string
+	for i, r := range resources {
+		if i == 0 || r.Category != resources[i-1].Category {
+			if i > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, "*"+r.Category+"*")
+		}
+		lines = append(lines, formatResource(r))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resourcePage returns the resources on a page, which is clamped to the pages there are, and
+// the number of pages
+func resourcePage(resources []model.Resource, page int) ([]model.Resource, int, int) {
+	pages := (len(resources) + resourcesPerPage - 1) / resourcesPerPage
+	if pages == 0 {
+		pages = 1
+	}
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	end := (page + 1) * resourcesPerPage
+	if end > len(resources) {
+		end = len(resources)
+	}
+	return resources[page*resourcesPerPage : end], page, pages
+}
+
+// resourcePageKeyboard returns the Prev and Next buttons for a page of a view, which is a category
+// or "everything"
+func resourcePageKeyboard(view string, page, pages int) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("⬅️ Prev", fmt.Sprintf("//resources_page %s %d", view, page-1)))
+	}
+	if page < pages-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("Next ➡️", fmt.Sprintf("//resources_page %s %d", view, page+1)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// resourcesView returns the resources in a view, which is a category or "everything"
+func (cb *Cinnabot) resourcesView(view string) []model.Resource {
+	category := view
+	if view == "everything" {
+		category = ""
+	}
+	resources := cb.db.Resources(category)
+	sortResources(resources)
+	return resources
+}
+
+// resourcesPageText formats a page of a view, with the page number if there is more than one
+func resourcesPageText(resources []model.Resource, page, pages int) string {
+	text := "🤖: Here you go!"
+	if pages > 1 {
+		text += fmt.Sprintf(" (page %d of %d)", page+1, pages)
+	}
+	return text + "\n\n" + formatResources(resources)
+}
+
+// sendResources sends the first page of a view, which is a category or "everything"
+func (cb *Cinnabot) sendResources(chatID int64, view string) {
+	resources, page, pages := resourcePage(cb.resourcesView(view), 0)
+	text := resourcesPageText(resources, page, pages)
+	if pages == 1 {
+		cb.SendTextMessage(int(chatID), text)
+		return
+	}
+	cb.SendMessage(NewMessageWithButton(text, resourcePageKeyboard(view, page, pages), chatID))
+}
+
+// searchResourcesFor sends the resources and interest groups which match query
+func (cb *Cinnabot) searchResourcesFor(chatID int64, query string) {
+	results := searchResources(cb.resourcesView("everything"), query)
+	groups := searchGroups(cb.db.InterestGroups(), query)
+	if len(results) == 0 && len(groups) == 0 {
+		cb.SendTextMessage(int(chatID), "🤖: I couldn't find anything like that. Try /resources to see everything.")
+		return
+	}
+	text := "🤖: Here's what I found:"
+	if len(results) > 0 {
+		text += "\n\n" + formatResources(results)
+	}
+	if len(groups) == 0 {
+		cb.SendTextMessage(int(chatID), text)
+		return
+	}
+	text += "\n\n*interest groups*\n" + formatGroupLines(groups)
+	cb.SendMessage(NewMessageWithButton(text, tgbotapi.NewInlineKeyboardMarkup(groupButtons(groups)...), chatID))
+}
+
+// ResourcesPage shows another page of resources
+func (cb *Cinnabot) ResourcesPage(qry *Callback) {
+	if len(qry.Args) < 2 {
+		return
+	}
+	view := qry.Args[0]
+	page, err := strconv.Atoi(qry.Args[1])
+	if err != nil {
+		return
+	}
+	resources, page, pages := resourcePage(cb.resourcesView(view), page)
+	cb.SendMessage(EditedMessageWithButton(resourcesPageText(resources, page, pages), resourcePageKeyboard(view, page, pages), qry.ChatID, qry.MsgID))
+}
+
+// searchResources returns the resources whose name or value best match query, in the order of
+// their categories
+func searchResources(resources []model.Resource, query string) []model.Resource {
+	fields := make([][]string, len(resources))
+	for i, r := range resources {
+		fields[i] = []string{r.Name, r.Value}
+	}
+	matches := bestMatches(query, fields, maxResourceResults)
+	results := make([]model.Resource, len(matches))
+	for i, m := range matches {
+		results[i] = resources[m]
+	}
+	sortResources(results)
+	return results
+}
+
+// bestMatches returns the indices of up to limit items which best match query, best match first.
+// fields are the strings each item is matched by, and the best match among them counts.
+func bestMatches(query string, fields [][]string, limit int) []int {
+	scores := make([]int, len(fields))
+	best := 0
+	for i, targets := range fields {
+		for _, target := range targets {
+			if score := utils.FuzzyScore(query, target); score > scores[i] {
+				scores[i] = score
+			}
+		}
+		if scores[i] > best {
+			best = scores[i]
+		}
+	}
+
+	matches := make([]int, 0)
+	for i, score := range scores {
+		if score > 0 && (score > weakResourceMatch || best <= weakResourceMatch) {
+			matches = append(matches, i)
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return scores[matches[a]] > scores[matches[b]]
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// parseResourceArgs parses the arguments of /editresources add, edit and remove, eg.
+// "links USC = [USC web](https://nususc.com)". The value is only parsed if withValue is set.
+func parseResourceArgs(args []string, withValue bool) (category resourceCategory, name, value string, err error) {
+	if len(args) < 2 {
+		return category, "", "", errors.New("missing category or name")
+	}
+	category, ok := findResourceCategory(args[0])
+	if !ok {
+		return category, "", "", fmt.Errorf("unknown category %s", args[0])
+	}
+	rest := strings.Join(args[1:], " ")
+	if !withValue {
+		return category, strings.TrimSpace(rest), "", nil
+	}
+	i := strings.Index(rest, "=")
+	if i < 0 {
+		return category, "", "", errors.New("missing = between the name and value")
+	}
+	name, value = strings.TrimSpace(rest[:i]), strings.TrimSpace(rest[i+1:])
+	if name == "" || value == "" {
+		return category, "", "", errors.New("missing name or value")
+	}
+	return category, name, value, nil
+}
+
+// formatResourceChange describes a change to the resources for the audit log
+func formatResourceChange(c model.ResourceChange) string {
+	who := "Cinnabot"
+	if c.AdminID != 0 {
+		who = "admin " + strconv.Itoa(c.AdminID)
+	}
+	text := c.CreatedAt.Format("2 Jan 15:04") + " · " + who + " "
+	switch c.Action {
+	case "add":
+		text += fmt.Sprintf("added %s/%s: %s", c.Category, c.Name, c.NewValue)
+	case "edit":
+		text += fmt.Sprintf("edited %s/%s: %s → %s", c.Category, c.Name, c.OldValue, c.NewValue)
+	case "remove":
+		text += fmt.Sprintf("removed %s/%s: %s", c.Category, c.Name, c.OldValue)
+	default:
+		text += "imported all resources"
+	}
+	return text
+}
+
+// editResourcesUsage is sent without Markdown, so that the example is shown as typed
+const editResourcesUsage = "🤖: Edit the entries shown by /resources:\n" +
+	"/editresources add <category> <name> = <value>\n" +
+	"/editresources edit <category> <name> = <value>\n" +
+	"/editresources remove <category> <name>\n" +
+	"/editresources export : get all the resources as a JSON file\n" +
+	"/editresources import : replace all the resources with a JSON file\n" +
+	"/editresources log : see recent changes\n\n" +
+	"Categories are telegram and links; interest groups are edited with /editgroups. " +
+	"Values are Markdown, so escape underscores in usernames, eg. @some\\_name"
+
+// EditResources lets admins add, edit and remove resources, import and export them as JSON, and
+// see who changed what
+func (cb *Cinnabot) EditResources(msg *message) {
+	if !cb.isAdmin(msg.From.ID) {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Only admins can edit resources.")
+		return
+	}
+
+	// File sent after /editresources import
+	if msg.Document != nil {
+		cb.receiveResourcesFile(msg)
+		return
+	}
+
+	if len(msg.Args) == 0 || msg.Args[0] == "" {
+		cb.SendMessage(tgbotapi.NewMessage(msg.Chat.ID, editResourcesUsage))
+		return
+	}
+
+	switch strings.ToLower(msg.Args[0]) {
+	case "add":
+		cb.editResource(msg, true)
+	case "edit":
+		cb.editResource(msg, false)
+	case "remove":
+		cb.removeResource(msg)
+	case "export":
+		cb.exportResources(msg.Chat.ID)
+	case "import":
+		cb.startResourceImport(msg)
+	case "log":
+		cb.resourceLog(msg.Chat.ID)
+	default:
+		cb.SendMessage(tgbotapi.NewMessage(msg.Chat.ID, editResourcesUsage))
+	}
+}
+
+// receiveResourcesFile imports the file an admin sends after /editresources import
+func (cb *Cinnabot) receiveResourcesFile(msg *message) {
+	if _, ok := cb.cache.Get(resourceImportKey(msg.From.ID)); !ok {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Send /editresources import first.")
+		return
+	}
+	cb.cache.Delete(resourceImportKey(msg.From.ID))
+	cb.importResources(msg)
+}
+
+// editResource handles /editresources add and /editresources edit
+func (cb *Cinnabot) editResource(msg *message, add bool) {
+	category, name, value, err := parseResourceArgs(msg.Args[1:], true)
+	if err != nil {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: I couldn't read that ("+err.Error()+"). Send /editresources to see how.")
+		return
+	}
+	if add {
+		err = cb.db.AddResource(msg.From.ID, category.Key, name, value)
+	} else {
+		err = cb.db.EditResource(msg.From.ID, category.Key, name, value)
+	}
+	if err != nil {
+		cb.sendResourceEditError(msg.Chat.ID, err)
+		return
+	}
+	cb.sendResourcePreview(msg.Chat.ID, "🤖: Saved! It now shows as:\n\n"+formatResource(model.Resource{Name: name, Value: value}))
+}
+
+// removeResource handles /editresources remove
+func (cb *Cinnabot) removeResource(msg *message) {
+	category, name, _, err := parseResourceArgs(msg.Args[1:], false)
+	if err != nil {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: I couldn't read that ("+err.Error()+"). Send /editresources to see how.")
+		return
+	}
+	if err := cb.db.DeleteResource(msg.From.ID, category.Key, name); err != nil {
+		cb.sendResourceEditError(msg.Chat.ID, err)
+		return
+	}
+	cb.SendTextMessage(int(msg.Chat.ID), "🤖: Removed.")
+}
+
+// exportResources sends all the resources as a JSON file
+func (cb *Cinnabot) exportResources(chatID int64) {
+	data, err := formatResourcesJSON(cb.db.Resources(""))
+	if err != nil {
+		cb.log.Printf("cannot export resources: %s", err)
+		cb.SendTextMessage(int(chatID), "🤖: Sorry, I couldn't export the resources.")
+		return
+	}
+	doc := tgbotapi.NewDocumentUpload(chatID, tgbotapi.FileBytes{Name: "resources.json", Bytes: data})
+	doc.Caption = "Edit this file and send it back after /editresources import to update all the resources."
+	cb.SendMessage(doc)
+}
+
+// startResourceImport waits for the admin to send a file of resources
+func (cb *Cinnabot) startResourceImport(msg *message) {
+	cb.cache.Set(resourceImportKey(msg.From.ID), true, cache.DefaultExpiration)
+	cb.SendTextMessage(int(msg.Chat.ID), "🤖: Send me the resources as a JSON file, in the same format as /editresources export. "+
+		"It will replace all the resources. Use /cancel to stop.")
+}
+
+// resourceLog sends the latest changes to the resources
+func (cb *Cinnabot) resourceLog(chatID int64) {
+	changes := cb.db.ResourceChanges(resourceChangesShown)
+	if len(changes) == 0 {
+		cb.SendMessage(tgbotapi.NewMessage(chatID, "🤖: No changes yet."))
+		return
+	}
+	lines := []string{"🤖: Recent changes:"}
+	for _, c := range changes {
+		lines = append(lines, formatResourceChange(c))
+	}
+	// Sent without Markdown, as the values may not be valid on their own
+	replyMsg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n"))
+	replyMsg.DisableWebPagePreview = true
+	cb.SendMessage(replyMsg)
+}
+
+// sendResourceEditError tells an admin why their change could not be saved
+func (cb *Cinnabot) sendResourceEditError(chatID int64, err error) {
+	switch {
+	case errors.Is(err, model.ErrResourceExists):
+		cb.SendTextMessage(int(chatID), "🤖: That entry already exists. Use /editresources edit to change it.")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		cb.SendTextMessage(int(chatID), "🤖: There's no entry with that name. Check /resources for the exact name.")
+	default:
+		cb.log.Printf("cannot edit resources: %s", err)
+		cb.SendTextMessage(int(chatID), "🤖: Sorry, I couldn't save the change. Please try again later.")
+	}
+}
+
+// sendResourcePreview shows an admin how their change looks in /resources, warning them if the
+// value is not valid Markdown, which would stop the whole category from being shown
+func (cb *Cinnabot) sendResourcePreview(chatID int64, text string) {
+	if err := cb.SendTextMessage(int(chatID), text); err != nil {
+		cb.SendMessage(tgbotapi.NewMessage(chatID, "🤖: Saved, but it isn't valid Markdown, so /resources may not be shown. "+
+			"Check for unescaped _ or * and edit it again."))
+	}
+}
+
+// importResources replaces all the resources with those in the file in msg
+func (cb *Cinnabot) importResources(msg *message) {
+	resources, err := cb.readResourcesFile(msg.Document)
+	if err != nil {
+		cb.log.Printf("cannot import resources: %s", err)
+		cb.SendMessage(tgbotapi.NewMessage(msg.Chat.ID, "🤖: I couldn't read that file: "+err.Error()))
+		return
+	}
+	if err := cb.db.ReplaceResources(msg.From.ID, resources); err != nil {
+		cb.log.Printf("cannot import resources: %s", err)
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Sorry, I couldn't save the resources. Please try again later.")
+		return
+	}
+	cb.SendTextMessage(int(msg.Chat.ID), fmt.Sprintf("🤖: Imported %d resources.", len(resources)))
+}
+
+// readResourcesFile downloads and parses a resources file sent to the bot
+func (cb *Cinnabot) readResourcesFile(doc *tgbotapi.Document) ([]model.Resource, error) {
+	if doc.FileSize > maxResourcesFileSize {
+		return nil, errors.New("it is too large")
+	}
+	url, err := cb.bot.GetFileDirectURL(doc.FileID)
+	if err != nil {
+		return nil, err
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		// The error would include the URL, which contains the bot's token
+		return nil, errors.New("download failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResourcesFileSize))
+	if err != nil {
+		return nil, err
+	}
+	return parseResources(data)
+}
@@ -0,0 +1,201 @@
+package cinnabot
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/usdevs/cinnabot/model"
+)
+
+func TestResourcesData(t *testing.T) {
+	data, err := ioutil.ReadFile("main/resources.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := parseResources(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := make(map[string]int)
+	for _, r := range resources {
+		count[r.Category]++
+	}
+	for _, c := range resourceCategories {
+		if count[c.Key] == 0 {
+			t.Errorf("no resources in %s", c.Key)
+		}
+	}
+}
+
+func TestResourcesJSON(t *testing.T) {
+	// Interest groups are left out, as they are kept separately
+	resources, err := parseResources([]byte(`{"links": {"USC": "[USC web](https://nususc.com)"}, "telegram": {"Food": "@rcmealbot", "Announcements": "@a"}, "interest_groups": {"Tennis": "@a"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []model.Resource{
+		{Category: "telegram", Name: "Announcements", Value: "@a"},
+		{Category: "telegram", Name: "Food", Value: "@rcmealbot"},
+		{Category: "links", Name: "USC", Value: "[USC web](https://nususc.com)"},
+	}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Fatalf("expected %v, got %v", expected, resources)
+	}
+
+	data, err := formatResourcesJSON(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := parseResources(data)
+	if err != nil || !reflect.DeepEqual(again, expected) {
+		t.Errorf("exported resources did not import the same: %v %s", err, data)
+	}
+
+	if _, err := parseResources([]byte(`{"groups": {"Tennis": "@a"}}`)); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+	if _, err := parseResources([]byte(`{"links": ["USC"]}`)); err == nil {
+		t.Error("expected an error for a list")
+	}
+}
+
+func TestParseResourceArgs(t *testing.T) {
+	tests := []struct {
+		args        []string
+		withValue   bool
+		category    string
+		name, value string
+		ok          bool
+	}{
+		{[]string{"telegram", "Supper", "Jio", "=", "@someone"}, true, "telegram", "Supper Jio", "@someone", true},
+		{[]string{"Links", "USC=[USC", "web](https://nususc.com)"}, true, "links", "USC", "[USC web](https://nususc.com)", true},
+		{[]string{"telegram", "Food", "=", "a=b"}, true, "telegram", "Food", "a=b", true},
+		{[]string{"telegram", "Supper", "Jio"}, false, "telegram", "Supper Jio", "", true},
+		{[]string{"telegram", "Supper", "Jio"}, true, "", "", "", false},
+		{[]string{"telegram", "=", "@someone"}, true, "", "", "", false},
+		{[]string{"interest", "Chess", "=", "@a"}, true, "", "", "", false},
+		{[]string{"links"}, false, "", "", "", false},
+	}
+	for _, test := range tests {
+		category, name, value, err := parseResourceArgs(test.args, test.withValue)
+		if (err == nil) != test.ok {
+			t.Errorf("%v: expected ok %v, got error %v", test.args, test.ok, err)
+			continue
+		}
+		if test.ok && (category.Key != test.category || name != test.name || value != test.value) {
+			t.Errorf("%v: expected %q %q %q, got %q %q %q", test.args, test.category, test.name, test.value, category.Key, name, value)
+		}
+	}
+}
+
+func TestFormatResourceChange(t *testing.T) {
+	at := time.Date(2020, 8, 3, 14, 5, 0, 0, time.UTC)
+	change := model.ResourceChange{AdminID: 42, Action: "edit", Category: "groups", Name: "Tennis (contacts)", OldValue: "@a", NewValue: "@b"}
+	change.CreatedAt = at
+	if got, want := formatResourceChange(change), "3 Aug 14:05 · admin 42 edited groups/Tennis (contacts): @a → @b"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	change = model.ResourceChange{Action: "import"}
+	change.CreatedAt = at
+	if got, want := formatResourceChange(change), "3 Aug 14:05 · Cinnabot imported all resources"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestSearchResources(t *testing.T) {
+	data, err := ioutil.ReadFile("main/resources.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := parseResources(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"supper", "Supper Jio"},
+		{"USC", "USC"},
+		{"fault", "Fault Reporting"},
+	}
+	for _, test := range tests {
+		results := searchResources(resources, test.query)
+		found := false
+		for _, r := range results {
+			if r.Name == test.expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("searchResources(%q): expected %q in %v", test.query, test.expected, results)
+		}
+	}
+
+	// Values are searched too, and weak matches are left out when there are better ones
+	resources = []model.Resource{
+		{Category: "telegram", Name: "Food", Value: "@rcmealbot"},
+		{Category: "telegram", Name: "Supper Jio", Value: "@SupperJio\\_bot"},
+		{Category: "links", Name: "Spaces", Value: "[Spaces web](https://www.nususc.com/spaces)"},
+	}
+	results := searchResources(resources, "bot")
+	if len(results) != 2 || results[0].Name != "Food" || results[1].Name != "Supper Jio" {
+		t.Errorf("expected both bots, got %v", results)
+	}
+	results = searchResources(resources, "nususc")
+	if len(results) != 1 || results[0].Name != "Spaces" {
+		t.Errorf("expected Spaces, got %v", results)
+	}
+	if results := searchResources(resources, "zzz"); len(results) != 0 {
+		t.Errorf("expected no results, got %v", results)
+	}
+}
+
+func TestResourcePages(t *testing.T) {
+	var resources []model.Resource
+	for i := 0; i < 2*resourcesPerPage+1; i++ {
+		resources = append(resources, model.Resource{Category: "links", Name: string(rune('A' + i))})
+	}
+	tests := []struct {
+		page, expectedPage, expectedLen int
+	}{
+		{0, 0, resourcesPerPage},
+		{2, 2, 1},
+		{5, 2, 1},
+		{-1, 0, resourcesPerPage},
+	}
+	for _, test := range tests {
+		onPage, page, pages := resourcePage(resources, test.page)
+		if page != test.expectedPage || len(onPage) != test.expectedLen || pages != 3 {
+			t.Errorf("page %d: expected page %d with %d resources of 3 pages, got page %d with %d of %d",
+				test.page, test.expectedPage, test.expectedLen, page, len(onPage), pages)
+		}
+	}
+	if _, _, pages := resourcePage(nil, 0); pages != 1 {
+		t.Errorf("expected 1 page without resources, got %d", pages)
+	}
+
+	keyboard := resourcePageKeyboard("everything", 1, 3)
+	row := keyboard.InlineKeyboard[0]
+	if len(row) != 2 || *row[0].CallbackData != "//resources_page everything 0" || *row[1].CallbackData != "//resources_page everything 2" {
+		t.Errorf("unexpected keyboard %v", row)
+	}
+	if row := resourcePageKeyboard("everything", 2, 3).InlineKeyboard[0]; len(row) != 1 || row[0].Text != "⬅️ Prev" {
+		t.Errorf("expected only Prev on the last page, got %v", row)
+	}
+}
+
+func TestFormatResources(t *testing.T) {
+	resources := []model.Resource{
+		{Category: "links", Name: "USC", Value: "@a"},
+		{Category: "telegram", Name: "Food", Value: "@rcmealbot"},
+		{Category: "links", Name: "Spaces", Value: "@b"},
+	}
+	sortResources(resources)
+	expected := "*telegram*\nFood : @rcmealbot\n\n*links*\nSpaces : @b\nUSC : @a"
+	if got := formatResources(resources); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
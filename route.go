@@ -0,0 +1,386 @@
+package cinnabot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/usdevs/cinnabot/transit"
+	"github.com/usdevs/cinnabot/utils"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// maxTransferOptions is the most one-transfer routes suggested
+const maxTransferOptions = 3
+
+// busRoute is the sequence of stops served by an NUS shuttle service. Services which loop
+// back to where they started list the first stop again at the end.
+type busRoute struct {
+	Service string   `json:"service"`
+	Stops   []string `json:"stops"` // stop codes, as in nusstops.json
+}
+
+// readRoutes reads the NUS shuttle routes from a JSON file (eg. nusroutes.json)
+func readRoutes(path string) ([]busRoute, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var routes struct {
+		Services []busRoute `json:"services"`
+	}
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return routes.Services, nil
+}
+
+// ride returns the fewest number of stops the service takes to go from one stop to another
+func (r busRoute) ride(from, to string) (int, bool) {
+	if from == to {
+		return 0, false
+	}
+	n := len(r.Stops)
+	loop := n > 1 && r.Stops[0] == r.Stops[n-1]
+	best := -1
+	for i, a := range r.Stops {
+		if a != from {
+			continue
+		}
+		for j, b := range r.Stops {
+			if b != to || i == j {
+				continue
+			}
+			stops := j - i
+			if stops < 0 {
+				if !loop {
+					continue
+				}
+				stops += n - 1
+			}
+			if stops > 0 && (best < 0 || stops < best) {
+				best = stops
+			}
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return best, true
+}
+
+// routeLeg is a ride on a single service
+type routeLeg struct {
+	Service string
+	Board   string // stop code
+	Alight  string // stop code
+	Stops   int
+}
+
+// routeOption is a way of getting from one place to another, with up to one transfer
+type routeOption []routeLeg
+
+func (o routeOption) stops() int {
+	total := 0
+	for _, leg := range o {
+		total += leg.Stops
+	}
+	return total
+}
+
+// bestRide returns the shortest ride on the service from any of the stops in from to any of the stops in to
+func bestRide(route busRoute, from, to []string) (routeLeg, bool) {
+	best := routeLeg{Service: route.Service}
+	for _, f := range from {
+		for _, t := range to {
+			if stops, ok := route.ride(f, t); ok && (best.Stops == 0 || stops < best.Stops) {
+				best = routeLeg{Service: route.Service, Board: f, Alight: t, Stops: stops}
+			}
+		}
+	}
+	return best, best.Stops > 0
+}
+
+// planRoutes finds the direct services from any of the stops in from to any of the stops in to,
+// and the best few options with one transfer using services which do not go there directly.
+// Transfers are only suggested if they take fewer stops than the best direct service.
+// Options are sorted by the number of stops travelled.
+func planRoutes(routes []busRoute, from, to []string) (direct, transfers []routeOption) {
+	isDirect := make(map[string]bool)
+	for _, route := range routes {
+		if leg, ok := bestRide(route, from, to); ok {
+			direct = append(direct, routeOption{leg})
+			isDirect[route.Service] = true
+		}
+	}
+
+	for _, first := range routes {
+		if isDirect[first.Service] {
+			continue
+		}
+		for _, second := range routes {
+			if second.Service == first.Service {
+				continue
+			}
+			// Try transferring at every stop of the first service
+			var best routeOption
+			for _, stop := range first.Stops {
+				if contains(from, stop) || contains(to, stop) {
+					continue
+				}
+				leg1, ok := bestRide(first, from, []string{stop})
+				if !ok {
+					continue
+				}
+				leg2, ok := bestRide(second, []string{stop}, to)
+				if !ok {
+					continue
+				}
+				option := routeOption{leg1, leg2}
+				if best == nil || option.stops() < best.stops() {
+					best = option
+				}
+			}
+			if best != nil {
+				transfers = append(transfers, best)
+			}
+		}
+	}
+
+	sort.SliceStable(direct, func(i, j int) bool { return direct[i].stops() < direct[j].stops() })
+	sort.SliceStable(transfers, func(i, j int) bool { return transfers[i].stops() < transfers[j].stops() })
+	if len(direct) > 0 {
+		for i, option := range transfers {
+			if option.stops() >= direct[0].stops() {
+				transfers = transfers[:i]
+				break
+			}
+		}
+	}
+	if len(transfers) > maxTransferOptions {
+		transfers = transfers[:maxTransferOptions]
+	}
+	return direct, transfers
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// routePlace is a place which can be routed to or from, and the NUS shuttle stops serving it
+type routePlace struct {
+	Name  string
+	Codes []string
+}
+
+// resolvePlace finds the stops for a place, which can be one of the locations of /nusbus, a stop
+// code, or a stop name. The stop opposite a matching stop is included, as the user may take either.
+func resolvePlace(query string, stops []transit.Stop) (routePlace, bool) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if alias, ok := aliases[query]; ok {
+		query = alias
+	}
+	if codes, ok := nusBusStopLocations[query]; ok {
+		return routePlace{Name: query, Codes: codes}, true
+	}
+
+	names := make([]string, len(stops))
+	for i, stop := range stops {
+		if strings.ToLower(stop.Code) == query {
+			return routePlace{Name: stop.Name, Codes: withOpposite(stop.Code, stops)}, true
+		}
+		names[i] = stop.Name
+	}
+	matches := utils.FuzzyRank(query, names)
+	if len(matches) == 0 {
+		return routePlace{}, false
+	}
+	stop := stops[matches[0]]
+	return routePlace{Name: stop.Name, Codes: withOpposite(stop.Code, stops)}, true
+}
+
+// withOpposite returns the code, and the code of the stop across the road if there is one
+func withOpposite(code string, stops []transit.Stop) []string {
+	opposite := code + "-OPP"
+	if strings.HasSuffix(code, "-OPP") {
+		opposite = strings.TrimSuffix(code, "-OPP")
+	}
+	for _, stop := range stops {
+		if stop.Code == opposite {
+			return []string{code, opposite}
+		}
+	}
+	return []string{code}
+}
+
+// parseRouteArgs splits the arguments of /route into the origin and destination, either side of
+// "to" if it is given, eg. "/route kent ridge mrt to utown" or "/route kr-mrt utown"
+func parseRouteArgs(args []string) (string, string, bool) {
+	for i, arg := range args {
+		if strings.ToLower(arg) == "to" && i > 0 && i < len(args)-1 {
+			return strings.Join(args[:i], " "), strings.Join(args[i+1:], " "), true
+		}
+	}
+	if len(args) == 2 {
+		return args[0], args[1], true
+	}
+	return "", "", false
+}
+
+// routeResponse lists the ways of getting between two places, with the live timings of the first bus of each
+func routeResponse(client *transit.Client, routes []busRoute, from, to routePlace, now time.Time) string {
+	stops, err := client.Stops()
+	if err != nil {
+		log.Print(err)
+	}
+	names := make(map[string]string)
+	for _, stop := range stops {
+		names[stop.Code] = stop.Name
+	}
+	name := func(code string) string {
+		if name, ok := names[code]; ok {
+			return name
+		}
+		return code
+	}
+
+	lines := []string{"🤖: Getting from *" + from.Name + "* to *" + to.Name + "*"}
+	for _, code := range from.Codes {
+		if contains(to.Codes, code) {
+			lines = append(lines, "You're already there!")
+			return strings.Join(lines, "\n")
+		}
+	}
+	direct, transfers := planRoutes(routes, from.Codes, to.Codes)
+	if len(direct) == 0 && len(transfers) == 0 {
+		lines = append(lines, "I couldn't find a way there by shuttle bus with at most one transfer.")
+		return strings.Join(lines, "\n")
+	}
+
+	// Fetch timings at every stop where a first bus is boarded
+	boardAt := make([]string, 0)
+	for _, option := range append(append([]routeOption{}, direct...), transfers...) {
+		if !contains(boardAt, option[0].Board) {
+			boardAt = append(boardAt, option[0].Board)
+		}
+	}
+	timings := make(map[string]transit.StopArrivals)
+	for _, result := range client.ArrivalsAt(context.Background(), boardAt) {
+		timings[result.Code] = result
+	}
+
+	if len(direct) > 0 {
+		lines = append(lines, "*Direct*")
+		for _, option := range direct {
+			leg := option[0]
+			lines = append(lines, fmt.Sprintf("🚍%s from %s, %d stops : %s",
+				leg.Service, name(leg.Board), leg.Stops, formatRouteTiming(timings[leg.Board], leg.Service, now)))
+		}
+	}
+	if len(transfers) > 0 {
+		lines = append(lines, "*With one transfer*")
+		for _, option := range transfers {
+			first, second := option[0], option[1]
+			lines = append(lines, fmt.Sprintf("🚍%s from %s, change at %s to 🚍%s, %d stops : %s",
+				first.Service, name(first.Board), name(first.Alight), second.Service, option.stops(),
+				formatRouteTiming(timings[first.Board], first.Service, now)))
+		}
+	}
+	lines = append(lines, "", "Last updated: "+now.Format(time.RFC822))
+	return strings.Join(lines, "\n")
+}
+
+// formatRouteTiming formats the timings of the service at a stop
+func formatRouteTiming(result transit.StopArrivals, service string, now time.Time) string {
+	if result.Err != nil {
+		log.Print(result.Err)
+		return "timings unavailable"
+	}
+	for _, arrival := range result.Arrivals {
+		if arrival.Service == service && len(arrival.Buses) > 0 {
+			return formatNUSBuses(arrival.Buses, now)
+		}
+	}
+	return "not operating now"
+}
+
+const routeUsageStr = "🤖: Where are you going? Send me where you're starting from and your destination, eg.\n" +
+	"/route utown to com2\n/route kent ridge mrt to science\n\n" +
+	"You can use the places from /nusbus, bus stop names or bus stop codes."
+
+// Route plans a trip on the NUS shuttle buses between two places
+func (cb *Cinnabot) Route(msg *message) {
+	fromQuery, toQuery, ok := parseRouteArgs(msg.Args)
+	if !ok {
+		cb.SendTextMessage(int(msg.Chat.ID), routeUsageStr)
+		return
+	}
+	cb.sendRoute(msg.Chat.ID, 0, fromQuery, toQuery)
+}
+
+// RouteRefresh handles the refresh button for messages from /route
+func (cb *Cinnabot) RouteRefresh(qry *Callback) {
+	fromQuery, toQuery, ok := parseRouteArgs(qry.Args)
+	if !ok {
+		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while refreshing the route")
+		return
+	}
+	cb.sendRoute(qry.ChatID, qry.MsgID, fromQuery, toQuery)
+}
+
+// sendRoute sends the routes between two places, editing the message msgID if it is not 0
+func (cb *Cinnabot) sendRoute(chatID int64, msgID int, fromQuery, toQuery string) {
+	if len(cb.nusRoutes) == 0 {
+		cb.SendTextMessage(int(chatID), "🤖: Sorry, route planning is unavailable right now.")
+		return
+	}
+	stops, err := cb.nusBus.Stops()
+	if err != nil {
+		log.Print(err)
+		cb.SendTextMessage(int(chatID), "Something went wrong while planning your route")
+		return
+	}
+	from, ok := resolvePlace(fromQuery, stops)
+	if !ok {
+		cb.SendTextMessage(int(chatID), "🤖: I don't know where \""+escapeMarkdown(fromQuery)+"\" is. Try a place from /nusbus or a bus stop name.")
+		return
+	}
+	to, ok := resolvePlace(toQuery, stops)
+	if !ok {
+		cb.SendTextMessage(int(chatID), "🤖: I don't know where \""+escapeMarkdown(toQuery)+"\" is. Try a place from /nusbus or a bus stop name.")
+		return
+	}
+
+	responseString := routeResponse(cb.nusBus, cb.nusRoutes, from, to, time.Now())
+
+	// very long queries cannot be refreshed, as they do not fit in callback data
+	refresh := "//route_refresh " + fromQuery + " to " + toQuery
+	if len(refresh) > maxCallbackDataLen {
+		if msgID != 0 {
+			cb.SendMessage(EditedMessage(responseString, chatID, msgID))
+		} else {
+			cb.SendTextMessage(int(chatID), responseString)
+		}
+		return
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Refresh", refresh),
+		),
+	)
+	if msgID != 0 {
+		cb.SendMessage(EditedMessageWithButton(responseString, keyboard, chatID, msgID))
+	} else {
+		cb.SendMessage(NewMessageWithButton(responseString, keyboard, chatID))
+	}
+}
@@ -0,0 +1,127 @@
+package cinnabot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/usdevs/cinnabot/transit"
+)
+
+var testRoutes = []busRoute{
+	{Service: "A", Stops: []string{"P", "Q", "R", "S", "P"}}, // loop
+	{Service: "B", Stops: []string{"R", "X", "Y"}},
+	{Service: "C", Stops: []string{"Q", "Y"}},
+}
+
+func TestRide(t *testing.T) {
+	tests := []struct {
+		service  busRoute
+		from, to string
+		stops    int
+		ok       bool
+	}{
+		{testRoutes[0], "Q", "S", 2, true},
+		{testRoutes[0], "S", "Q", 2, true}, // around the loop
+		{testRoutes[0], "P", "P", 0, false},
+		{testRoutes[1], "Y", "R", 0, false}, // not a loop
+		{testRoutes[1], "R", "Y", 2, true},
+	}
+	for _, test := range tests {
+		stops, ok := test.service.ride(test.from, test.to)
+		if stops != test.stops || ok != test.ok {
+			t.Errorf("%s from %s to %s: expected (%d, %t), got (%d, %t)",
+				test.service.Service, test.from, test.to, test.stops, test.ok, stops, ok)
+		}
+	}
+}
+
+func TestPlanRoutes(t *testing.T) {
+	direct, transfers := planRoutes(testRoutes, []string{"P"}, []string{"Y"})
+	if len(direct) != 0 {
+		t.Errorf("expected no direct routes, got %v", direct)
+	}
+	expected := []routeOption{
+		{{"A", "P", "Q", 1}, {"C", "Q", "Y", 1}},
+		{{"A", "P", "R", 2}, {"B", "R", "Y", 2}},
+	}
+	if !reflect.DeepEqual(transfers, expected) {
+		t.Errorf("expected transfers %v, got %v", expected, transfers)
+	}
+
+	direct, transfers = planRoutes(testRoutes, []string{"Q"}, []string{"Y"})
+	if !reflect.DeepEqual(direct, []routeOption{{{"C", "Q", "Y", 1}}}) {
+		t.Errorf("expected direct route on C, got %v", direct)
+	}
+	for _, option := range transfers {
+		if option[0].Service == "C" {
+			t.Errorf("expected no transfers from a direct service, got %v", option)
+		}
+	}
+}
+
+func TestNUSRoutesData(t *testing.T) {
+	routes, err := readRoutes("main/nusroutes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stops, err := transit.ReadStops("main/nusstops.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := make(map[string]bool)
+	for _, stop := range stops {
+		known[stop.Code] = true
+	}
+	for _, route := range routes {
+		for _, code := range route.Stops {
+			if !known[code] {
+				t.Errorf("service %s stops at unknown stop %q", route.Service, code)
+			}
+		}
+	}
+}
+
+func TestResolvePlace(t *testing.T) {
+	stops, err := transit.ReadStops("main/nusstops.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query    string
+		expected routePlace
+	}{
+		{"Science", routePlace{"science", []string{"S17", "LT27"}}},
+		{"kr", routePlace{"kr-mrt", []string{"KR-MRT", "KR-MRT-OPP"}}},
+		{"yih-opp", routePlace{"Opp YIH", []string{"YIH-OPP", "YIH"}}},
+		{"university town", routePlace{"University Town", []string{"UTown"}}},
+	}
+	for _, test := range tests {
+		got, ok := resolvePlace(test.query, stops)
+		if !ok || !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.query, test.expected, got)
+		}
+	}
+}
+
+func TestRouteResponse(t *testing.T) {
+	client := fakeNUSClient()
+	from := routePlace{"University Town", []string{"UTown"}}
+	to := routePlace{"S17", []string{"S17"}}
+
+	got := routeResponse(client, testNUSRoutes(t), from, to, recordedTime)
+	expected := "🤖: Getting from *University Town* to *S17*\n" +
+		"*Direct*\n" +
+		"🚍D2 from University Town, 3 stops : 1 min, 12 mins\n" +
+		"\nLast updated: 15 Oct 20 10:00 +08"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func testNUSRoutes(t *testing.T) []busRoute {
+	routes, err := readRoutes("main/nusroutes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routes
+}
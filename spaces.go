@@ -9,6 +9,7 @@ import (
 
 	fs "github.com/usdevs/cinnabot/firestore"
 	"github.com/usdevs/cinnabot/utils"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
 )
 
 // Displaying:
@@ -33,6 +34,7 @@ func FormatTimeDate(t time.Time) string {
 
 // Event represents a booking
 type Event struct {
+	ID    string // name of the Firestore document, eg. "projects/<project>/databases/(default)/documents/events/<id>"
 	Name  string
 	Venue string
 	Start time.Time
@@ -167,6 +169,13 @@ func (e eventData) toEvent() Event {
 
 // getSpacesAfter returns the Events (as Spaces) whose endDate is after the specified date
 func getSpacesAfter(date time.Time) Spaces {
+	spaces, _ := fetchSpacesAfter(date)
+	return spaces
+}
+
+// fetchSpacesAfter is getSpacesAfter, but returns an error if the events could not be fetched,
+// so that callers can tell it apart from there being no events
+func fetchSpacesAfter(date time.Time) (Spaces, error) {
 	query := fs.Query{
 		From: []fs.CollectionSelector{{CollectionId: "events", AllDescendants: false}},
 		Where: []fs.Filter{{
@@ -187,14 +196,16 @@ func getSpacesAfter(date time.Time) Spaces {
 	docs, err := fs.RunQueryAndParse(uscWebsiteProjectID, query, parse, false)
 
 	if err != nil {
-		return spaces
+		return spaces, err
 	}
 
 	for _, doc := range docs {
-		spaces.addEvent(doc.Data.(eventData).toEvent())
+		event := doc.Data.(eventData).toEvent()
+		event.ID = doc.Name
+		spaces.addEvent(event)
 	}
 
-	return spaces
+	return spaces, nil
 }
 
 // Filtering
@@ -360,6 +371,201 @@ func ParseDDMMYYDate(date string) (time.Time, error) {
 	return t, err
 }
 
+// Venues:
+
+// venueLookbackDays is how far back bookings are looked at to find the venues which can be booked
+const venueLookbackDays = 30
+
+// venueNames returns the names of the venues in spaces, sorted
+func (spaces Spaces) venueNames() []string {
+	names := make([]string, 0, len(spaces))
+	for _, space := range spaces {
+		if name := space.getName(); name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// space returns the Space for a venue, which is empty if there are no bookings there
+func (spaces Spaces) space(venue string) Space {
+	for _, space := range spaces {
+		if space.getName() == venue {
+			return space
+		}
+	}
+	return Space{}
+}
+
+// matchVenue returns the venue which best matches query
+func matchVenue(venues []string, query string) (string, bool) {
+	if strings.TrimSpace(query) == "" {
+		return "", false
+	}
+	matches := utils.FuzzyRank(query, venues)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return venues[matches[0]], true
+}
+
+// isSpacesPeriod checks if an argument of /spaces is a period, ie. a keyword like "week" or a date
+func isSpacesPeriod(arg string) bool {
+	switch arg {
+	case "now", "today", "tomorrow", "week":
+		return true
+	}
+	_, err := ParseDDMMYYDate(arg)
+	return err == nil
+}
+
+// splitVenueArgs splits the arguments of /spaces <venue> [period] into the venue and the period,
+// which is a keyword or up to two dates at the end
+func splitVenueArgs(args []string) (string, []string) {
+	i := len(args)
+	for i > 1 && len(args)-i < 2 && isSpacesPeriod(args[i-1]) {
+		i--
+	}
+	return strings.Join(args[:i], " "), args[i:]
+}
+
+// venuePeriod returns the events in a period at a venue, which defaults to the next 7 days, and
+// a description of the period. ok is false if the period is not understood.
+func venuePeriod(period []string, now time.Time) (predicate eventPredicate, from time.Time, description string, ok bool) {
+	now = now.In(utils.SgLocation())
+	if len(period) == 0 {
+		period = []string{"week"}
+	}
+	switch period[0] {
+	case "now":
+		return eventDuring(now), now, "right now", len(period) == 1
+	case "today":
+		return eventOnDay(now), startOfDay(now), "today", len(period) == 1
+	case "tomorrow":
+		tomorrow := now.AddDate(0, 0, 1)
+		return eventOnDay(tomorrow), startOfDay(tomorrow), "tomorrow", len(period) == 1
+	case "week":
+		weekLater := now.AddDate(0, 0, 7)
+		return eventBetweenDays(now, weekLater), startOfDay(now), fmt.Sprintf("7 days from now (%s to %s)", FormatDate(now), FormatDate(weekLater)), len(period) == 1
+	}
+
+	t0, err := ParseDDMMYYDate(period[0])
+	if err != nil {
+		return nil, time.Time{}, "", false
+	}
+	if len(period) == 1 {
+		return eventOnDay(t0), startOfDay(t0), "on " + FormatDate(t0), true
+	}
+	t1, err := ParseDDMMYYDate(period[1])
+	if err != nil || t0.AddDate(0, 0, 33).Before(t1) {
+		return nil, time.Time{}, "", false
+	}
+	return eventBetweenDays(t0, t1), startOfDay(t0), fmt.Sprintf("from %s to %s", FormatDate(t0), FormatDate(t1)), true
+}
+
+// venueBookingsMessage returns the events at a venue in a period
+func venueBookingsMessage(spaces Spaces, venue string, period []string, now time.Time) (string, bool) {
+	predicate, _, description, ok := venuePeriod(period, now)
+	if !ok {
+		return "", false
+	}
+	message := fmt.Sprintf("Displaying bookings at %s %s:\n\n", venue, description)
+	space := spaces.space(venue).filter(predicate)
+	if len(space) == 0 {
+		return message + "[No bookings recorded]", true
+	}
+	return message + space.toString(), true
+}
+
+// venueSpaces fetches the bookings needed to find venues and show a period at them
+func venueSpaces(period []string, now time.Time) Spaces {
+	after := now.AddDate(0, 0, -venueLookbackDays)
+	if _, from, _, ok := venuePeriod(period, now); ok && from.Before(after) {
+		after = from
+	}
+	return getSpacesAfter(after)
+}
+
+// makeVenueKeyboard returns a button for each venue, two to a row
+func makeVenueKeyboard(venues []string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(venues); i += 2 {
+		row := tgbotapi.NewInlineKeyboardRow(venueButton(venues[i]))
+		if i+1 < len(venues) {
+			row = append(row, venueButton(venues[i+1]))
+		}
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// venueButton returns a button which shows the bookings at a venue
+func venueButton(venue string) tgbotapi.InlineKeyboardButton {
+	return venueCommandButton(venue, "//spaces_venue", venue)
+}
+
+// venueCommandButton returns a button which runs command for a venue. Long names are cut short to
+// fit in the callback data, and are still matched by matchVenue.
+func venueCommandButton(text, command, venue string) tgbotapi.InlineKeyboardButton {
+	data := command + " " + venue
+	for len(data) > maxCallbackDataLen {
+		r := []rune(data)
+		data = string(r[:len(r)-1])
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, data)
+}
+
+// maxCallbackDataLen is the most bytes Telegram allows in the data of a button
+const maxCallbackDataLen = 64
+
+// spacesVenue sends the bookings at the venue in args, or the venues to choose from if it is not found
+func (cb *Cinnabot) spacesVenue(chatID int64, userID int, args []string) {
+	now := time.Now()
+	query, period := splitVenueArgs(args)
+	spaces := venueSpaces(period, now)
+	venues := spaces.venueNames()
+
+	venue, found := matchVenue(venues, query)
+	if found {
+		if text, ok := venueBookingsMessage(spaces, venue, period, now); ok {
+			keyboard := venueBookingsKeyboard(spaces, venue, period, cb.isWatching(userID, venue), now)
+			cb.SendMessage(NewMessageWithButton(text, keyboard, chatID))
+			return
+		}
+	}
+
+	text := "Cinnabot was unable to understand your command.\n\n" + spacesHelp
+	if len(venues) > 0 {
+		text = "Pick a venue to see its bookings for the next 7 days, or type '/spaces <venue> dd/mm' for a specific day."
+		if query != "" {
+			text = "Cinnabot couldn't find that venue. " + text
+		}
+	}
+	cb.SendMessage(NewMessageWithButton(text, makeVenueKeyboard(venues), chatID))
+}
+
+// SpacesVenue shows the bookings in the next 7 days at the venue picked from the keyboard
+func (cb *Cinnabot) SpacesVenue(qry *Callback) {
+	now := time.Now()
+	spaces := venueSpaces(nil, now)
+	venue, ok := matchVenue(spaces.venueNames(), qry.GetArgString())
+	if !ok {
+		cb.SendTextMessage(int(qry.ChatID), "Cinnabot couldn't find that venue any more.")
+		return
+	}
+	text, _ := venueBookingsMessage(spaces, venue, nil, now)
+	keyboard := venueBookingsKeyboard(spaces, venue, nil, cb.isWatching(qry.From.ID, venue), now)
+	cb.SendMessage(NewMessageWithButton(text, keyboard, qry.ChatID))
+}
+
+// spacesHelp explains how to use /spaces
+const spacesHelp = "To use the '/spaces' command, type one of the following:\n'/spaces' : to view all bookings for today\n'/spaces now' : to view bookings active at this very moment\n'/spaces week' : to view all bookings for this week\n'/spaces dd/mm(/yy)' : to view all bookings on a specific day\n'/spaces dd/mm(/yy) dd/mm(/yy)' : to view all bookings in a specific range of dates\n" +
+	"'/spaces <venue>' : to view bookings at a venue for the next 7 days, eg. '/spaces chill room'\n'/spaces <venue> dd/mm(/yy)' : to view bookings at a venue on a specific day\n'/spaces venues' : to pick a venue\n" +
+	"'/spaces ics [venue] [week or dd/mm(/yy) dd/mm(/yy)]' : to get the bookings as a calendar file\n" +
+	"'/spaces watch <venue>' : to be told about new bookings at a venue, or tap ⏰ under a venue's bookings to be reminded before one starts\n" +
+	"'/spaces alerts' : to see the venues you watch and your reminders"
+
 // for easier debugging
 func spacesMsg(msg *message) string {
 	toSend := ""
@@ -401,7 +607,7 @@ func spacesMsg(msg *message) string {
 			toSend += "Cinnabot was unable to understand your command.\n\n"
 		}
 
-		toSend += "To use the '/spaces' command, type one of the following:\n'/spaces' : to view all bookings for today\n'/spaces now' : to view bookings active at this very moment\n'/spaces week' : to view all bookings for this week\n'/spaces dd/mm(/yy)' : to view all bookings on a specific day\n'/spaces dd/mm(/yy) dd/mm(/yy)' : to view all bookings in a specific range of dates"
+		toSend += spacesHelp
 	}
 
 	return toSend
@@ -413,10 +619,32 @@ func spacesMsg(msg *message) string {
 //	"/spaces week" displays bookings in the next 7 days.
 //	"/spaces dd/mm/yy" displays bookings on the given date.
 //	"/spaces dd/mm/yy dd/mm/yy" displays bookings in the given interval (limited to one month++).
+//	"/spaces <venue> [period]" displays bookings at the venue in the next 7 days, or the given period.
+//	"/spaces venues" lists the venues to pick from.
+//	"/spaces watch <venue>" tells the user about new, changed and cancelled bookings at the venue.
+//	"/spaces alerts" lists the venues the user watches and the bookings they will be reminded of.
+//	"/spaces ics [venue] [period]" sends the bookings in the next 7 days, or the given period, as an iCalendar file.
 //	"/spaces help" informs the user of available commands.
 //
 //	Extra arguments are ignored.
 //	Unparseable commands return the help menu.
 func (cb *Cinnabot) Spaces(msg *message) {
+	if len(msg.Args) > 0 && msg.Args[0] != "help" && !isSpacesPeriod(msg.Args[0]) {
+		switch msg.Args[0] {
+		case "ics":
+			cb.spacesICS(int64(msg.From.ID), msg.Args[1:])
+			return
+		case "watch":
+			cb.toggleVenueWatch(int64(msg.From.ID), msg.From.ID, strings.Join(msg.Args[1:], " "))
+			return
+		case "alerts":
+			cb.spacesAlerts(int64(msg.From.ID), msg.From.ID)
+			return
+		case "venues":
+			msg.Args = msg.Args[1:]
+		}
+		cb.spacesVenue(int64(msg.From.ID), msg.From.ID, msg.Args)
+		return
+	}
 	cb.SendTextMessage(msg.From.ID, spacesMsg(msg))
 }
@@ -0,0 +1,355 @@
+package cinnabot
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usdevs/cinnabot/model"
+	"github.com/usdevs/cinnabot/utils"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// VenueWatchInterval is how often CheckVenueWatches should be run. Each check fetches every
+// upcoming booking from Firestore.
+const VenueWatchInterval = 5 * time.Minute
+
+// EventReminderInterval is how often CheckEventReminders should be run. It only reads the
+// database, so it runs often enough for reminders to be on time.
+const EventReminderInterval = time.Minute
+
+// eventReminderMinutes is how long before a booking starts its reminders are sent
+const eventReminderMinutes = 30
+
+// maxReminderButtons is the most bookings shown with a reminder button under the bookings at a venue
+const maxReminderButtons = 6
+
+// bookingChange is a booking which was added, changed or cancelled since the last check
+type bookingChange struct {
+	Kind  string // "new", "changed" or "cancelled"
+	Event Event
+	Old   Event // the booking before it changed, for "changed"
+}
+
+// venues returns the venues the change affects, which is two if the booking moved venue
+func (c bookingChange) venues() []string {
+	if c.Kind == "changed" && c.Old.Venue != c.Event.Venue {
+		return []string{c.Old.Venue, c.Event.Venue}
+	}
+	return []string{c.Event.Venue}
+}
+
+// String describes the change, eg. "🆕 New booking at *Chill Room*\n*Jam:* 03PM to 05PM, Mon 19 Nov 18"
+func (c bookingChange) String() string {
+	event := c.Event
+	event.Start, event.End = event.Start.In(utils.SgLocation()), event.End.In(utils.SgLocation())
+	switch c.Kind {
+	case "new":
+		return fmt.Sprintf("🆕 New booking at *%s*\n%s", event.Venue, event.toString())
+	case "cancelled":
+		return fmt.Sprintf("❌ Booking cancelled at *%s*\n%s", event.Venue, event.toString())
+	}
+	old := c.Old
+	old.Start, old.End = old.Start.In(utils.SgLocation()), old.End.In(utils.SgLocation())
+	text := fmt.Sprintf("✏️ Booking changed at *%s*\n%s\n(was %s", event.Venue, event.toString(), old.timeInfo())
+	if old.Venue != event.Venue {
+		text += " at " + old.Venue
+	}
+	return text + ")"
+}
+
+// spacesWatch remembers the upcoming bookings at the last check, by ID
+type spacesWatch struct {
+	mu     sync.Mutex
+	events map[string]Event
+}
+
+func newSpacesWatch() *spacesWatch {
+	return &spacesWatch{}
+}
+
+// diff records the latest bookings and returns how they changed since the last check, in order
+// of start time. Bookings missing from spaces count as cancelled only if they had not ended by now.
+func (sw *spacesWatch) diff(spaces Spaces, now time.Time) []bookingChange {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	events := make(map[string]Event)
+	for _, space := range spaces {
+		for _, event := range space {
+			if event.ID != "" {
+				events[event.ID] = event
+			}
+		}
+	}
+	previous := sw.events
+	sw.events = events
+	if previous == nil {
+		return nil
+	}
+
+	changes := make([]bookingChange, 0)
+	for id, event := range events {
+		old, known := previous[id]
+		if !known {
+			changes = append(changes, bookingChange{Kind: "new", Event: event})
+		} else if old.Name != event.Name || old.Venue != event.Venue || !old.Start.Equal(event.Start) || !old.End.Equal(event.End) {
+			changes = append(changes, bookingChange{Kind: "changed", Event: event, Old: old})
+		}
+	}
+	for id, old := range previous {
+		if _, ok := events[id]; !ok && old.End.After(now) {
+			changes = append(changes, bookingChange{Kind: "cancelled", Event: old})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].Event.Start.Equal(changes[j].Event.Start) {
+			return changes[i].Event.Start.Before(changes[j].Event.Start)
+		}
+		return changes[i].Event.ID < changes[j].Event.ID
+	})
+	return changes
+}
+
+// venueWatchMessages returns the message to send to each user watching a venue with changes, and
+// the users told about each change, by booking ID
+func venueWatchMessages(watches []model.VenueWatch, changes []bookingChange) (map[int]string, map[string]map[int]bool) {
+	watchers := make(map[string][]int)
+	for _, w := range watches {
+		watchers[w.Venue] = append(watchers[w.Venue], w.UserID)
+	}
+
+	lines := make(map[int][]string)
+	told := make(map[string]map[int]bool)
+	for _, c := range changes {
+		told[c.Event.ID] = make(map[int]bool)
+		for _, venue := range c.venues() {
+			for _, userID := range watchers[venue] {
+				if !told[c.Event.ID][userID] {
+					told[c.Event.ID][userID] = true
+					lines[userID] = append(lines[userID], c.String())
+				}
+			}
+		}
+	}
+
+	messages := make(map[int]string, len(lines))
+	for userID, l := range lines {
+		messages[userID] = strings.Join(l, "\n\n")
+	}
+	return messages, told
+}
+
+// CheckVenueWatches tells users watching a venue about new, changed and cancelled bookings there,
+// and updates or removes the reminders for changed and cancelled bookings.
+func (cb *Cinnabot) CheckVenueWatches() {
+	now := time.Now()
+	spaces, err := fetchSpacesAfter(now)
+	if err != nil {
+		cb.log.Printf("cannot check venue watches: %s", err)
+		return
+	}
+	changes := cb.spacesWatch.diff(spaces, now)
+	if len(changes) == 0 {
+		return
+	}
+
+	messages, told := venueWatchMessages(cb.db.VenueWatches(), changes)
+	for userID, text := range messages {
+		cb.SendTextMessage(userID, text)
+	}
+
+	reminders := make(map[string][]model.EventReminder)
+	for _, r := range cb.db.EventReminders() {
+		reminders[r.EventID] = append(reminders[r.EventID], r)
+	}
+	for _, c := range changes {
+		if c.Kind == "new" || len(reminders[c.Event.ID]) == 0 {
+			continue
+		}
+		var err error
+		if c.Kind == "cancelled" {
+			err = cb.db.DeleteEventReminders(c.Event.ID)
+		} else {
+			err = cb.db.UpdateEventReminders(c.Event.ID, c.Event.Name, c.Event.Venue, c.Event.Start)
+		}
+		if err != nil {
+			cb.log.Printf("cannot update reminders for %s: %s", c.Event.ID, err)
+		}
+		for _, r := range reminders[c.Event.ID] {
+			// users watching the venue have already been told
+			if told[c.Event.ID][r.UserID] {
+				continue
+			}
+			cb.SendTextMessage(r.UserID, "🤖: A booking you have a reminder for has changed.\n\n"+c.String())
+		}
+	}
+}
+
+// CheckEventReminders sends the reminders which are due. Reminders for bookings which have
+// already started, eg. while the bot was down, are dropped.
+func (cb *Cinnabot) CheckEventReminders() {
+	now := time.Now()
+	for _, r := range cb.db.EventReminders() {
+		if r.RemindAt().After(now) {
+			continue
+		}
+		if r.Start.After(now) {
+			text := fmt.Sprintf("⏰ *%s* starts at %s at %s.", r.Name, FormatTime(r.Start.In(utils.SgLocation())), r.Venue)
+			cb.SendTextMessage(r.UserID, text)
+		}
+		if err := cb.db.DeleteEventReminder(r.ID); err != nil {
+			cb.log.Printf("cannot delete reminder %d: %s", r.ID, err)
+		}
+	}
+}
+
+// findEvent returns the event whose ID ends with id, as sent in callback data
+func (spaces Spaces) findEvent(id string) (Event, bool) {
+	for _, space := range spaces {
+		for _, event := range space {
+			if event.ID != "" && path.Base(event.ID) == id {
+				return event, true
+			}
+		}
+	}
+	return Event{}, false
+}
+
+// venueBookingsKeyboard returns buttons to watch the venue, and to be reminded of its upcoming
+// bookings in the period
+func venueBookingsKeyboard(spaces Spaces, venue string, period []string, watching bool, now time.Time) tgbotapi.InlineKeyboardMarkup {
+	label := "🔔 Watch for new bookings"
+	if watching {
+		label = "🔕 Stop watching"
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(venueCommandButton(label, "//spaces_watch", venue))}
+
+	predicate, _, _, ok := venuePeriod(period, now)
+	if !ok {
+		return tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	upcoming := spaces.space(venue).filter(predicate).filter(func(e Event) bool { return e.ID != "" && e.Start.After(now) })
+	upcoming = upcoming.sortByStartDate()
+	for i, event := range upcoming {
+		if i == maxReminderButtons {
+			break
+		}
+		start := event.Start.In(utils.SgLocation())
+		text := fmt.Sprintf("⏰ %s, %s %s", event.Name, start.Format("Mon 02 Jan"), FormatTime(start))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, "//spaces_remind "+path.Base(event.ID))))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// isWatching checks if the user is watching the venue
+func (cb *Cinnabot) isWatching(userID int, venue string) bool {
+	for _, w := range cb.db.UserVenueWatches(userID) {
+		if w.Venue == venue {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedVenue returns the venue a watch button or command is for. Venues the user already
+// watches are included, so that they can stop watching venues with no bookings left.
+func (cb *Cinnabot) watchedVenue(userID int, query string) (string, bool) {
+	venues := getSpacesAfter(time.Now().AddDate(0, 0, -venueLookbackDays)).venueNames()
+	for _, w := range cb.db.UserVenueWatches(userID) {
+		venues = append(venues, w.Venue)
+	}
+	return matchVenue(venues, query)
+}
+
+// toggleVenueWatch watches or stops watching a venue, and tells the user which
+func (cb *Cinnabot) toggleVenueWatch(chatID int64, userID int, query string) {
+	venue, ok := cb.watchedVenue(userID, query)
+	if !ok {
+		cb.SendTextMessage(int(chatID), "🤖: Cinnabot couldn't find that venue. Type '/spaces venues' to see them all.")
+		return
+	}
+	watching, err := cb.db.ToggleVenueWatch(userID, venue)
+	if err != nil {
+		cb.log.Printf("cannot update venue watch: %s", err)
+		cb.SendTextMessage(int(chatID), "🤖: Something went wrong while watching the venue.")
+		return
+	}
+	if watching {
+		cb.SendTextMessage(int(chatID), "🤖: I'll tell you about new, changed and cancelled bookings at *"+venue+"*.")
+	} else {
+		cb.SendTextMessage(int(chatID), "🤖: Stopped watching *"+venue+"*.")
+	}
+}
+
+// SpacesWatch watches or stops watching the venue from the button under its bookings
+func (cb *Cinnabot) SpacesWatch(qry *Callback) {
+	cb.toggleVenueWatch(qry.ChatID, qry.From.ID, qry.GetArgString())
+}
+
+// SpacesRemind adds or removes a reminder for the booking from the button under its venue's bookings
+func (cb *Cinnabot) SpacesRemind(qry *Callback) {
+	userID, id := qry.From.ID, qry.GetArgString()
+	for _, r := range cb.db.UserEventReminders(userID) {
+		if path.Base(r.EventID) == id {
+			if _, err := cb.db.ToggleEventReminder(r); err != nil {
+				cb.log.Printf("cannot remove reminder: %s", err)
+				cb.SendTextMessage(int(qry.ChatID), "🤖: Something went wrong while removing the reminder.")
+				return
+			}
+			cb.SendTextMessage(int(qry.ChatID), "🤖: Removed the reminder for *"+r.Name+"*.")
+			return
+		}
+	}
+
+	now := time.Now()
+	event, ok := getSpacesAfter(now).findEvent(id)
+	if !ok || !event.Start.After(now) {
+		cb.SendTextMessage(int(qry.ChatID), "🤖: That booking has already started, or was cancelled.")
+		return
+	}
+	reminder := model.EventReminder{UserID: userID, EventID: event.ID, Name: event.Name, Venue: event.Venue, Start: event.Start, Before: eventReminderMinutes}
+	if _, err := cb.db.ToggleEventReminder(reminder); err != nil {
+		cb.log.Printf("cannot add reminder: %s", err)
+		cb.SendTextMessage(int(qry.ChatID), "🤖: Something went wrong while adding the reminder.")
+		return
+	}
+	cb.SendTextMessage(int(qry.ChatID), fmt.Sprintf("🤖: I'll remind you %d min before *%s* starts. Tap the button again to remove the reminder.", eventReminderMinutes, event.Name))
+}
+
+// spacesAlerts lists the venues the user watches and their reminders, with buttons to remove them
+func (cb *Cinnabot) spacesAlerts(chatID int64, userID int) {
+	watches := cb.db.UserVenueWatches(userID)
+	reminders := cb.db.UserEventReminders(userID)
+	if len(watches) == 0 && len(reminders) == 0 {
+		cb.SendTextMessage(int(chatID), "🤖: You aren't watching any venues or waiting for any reminders.\n\n"+
+			"Type '/spaces watch <venue>' to hear about new bookings at a venue, or tap ⏰ under the bookings from '/spaces <venue>' to be reminded before one starts.")
+		return
+	}
+
+	var sb strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(watches) > 0 {
+		sb.WriteString("🤖: You are watching for bookings at:\n")
+		for _, w := range watches {
+			sb.WriteString("• " + w.Venue + "\n")
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(venueCommandButton("🔕 "+w.Venue, "//spaces_watch", w.Venue)))
+		}
+		sb.WriteString("\n")
+	}
+	if len(reminders) > 0 {
+		sb.WriteString(fmt.Sprintf("You'll be reminded %d min before:\n", eventReminderMinutes))
+		for _, r := range reminders {
+			sb.WriteString(fmt.Sprintf("• *%s:* %s at %s\n", r.Name, FormatTimeDate(r.Start.In(utils.SgLocation())), r.Venue))
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔕 "+r.Name, "//spaces_remind "+path.Base(r.EventID))))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("Tap a button to remove it.")
+	cb.SendMessage(NewMessageWithButton(sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...), chatID))
+}
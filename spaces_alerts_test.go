@@ -0,0 +1,96 @@
+package cinnabot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/usdevs/cinnabot/model"
+	"github.com/usdevs/cinnabot/utils"
+)
+
+func TestSpacesWatchDiff(t *testing.T) {
+	now := time.Date(2018, 11, 19, 9, 0, 0, 0, utils.SgLocation())
+	jam := Event{ID: "events/jam", Name: "Jam", Venue: "Chill Room", Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}
+	talk := Event{ID: "events/talk", Name: "Talk", Venue: "Cinnamon Lounge", Start: now.Add(3 * time.Hour), End: now.Add(4 * time.Hour)}
+	ending := Event{ID: "events/ending", Name: "Ending", Venue: "Chill Room", Start: now.Add(-time.Hour), End: now.Add(time.Minute)}
+
+	sw := newSpacesWatch()
+	if changes := sw.diff(Spaces{Space{jam, ending}, Space{talk}}, now); len(changes) != 0 {
+		t.Fatalf("first check returned changes: %v", changes)
+	}
+
+	// jam moves, talk is cancelled, ending ends and a quiz is added
+	later := now.Add(5 * time.Minute)
+	moved := jam
+	moved.Venue = "Cinnamon Lounge"
+	quiz := Event{ID: "events/quiz", Name: "Quiz", Venue: "Chill Room", Start: now.Add(5 * time.Hour), End: now.Add(6 * time.Hour)}
+	changes := sw.diff(Spaces{Space{moved, quiz}}, later)
+
+	want := []string{"changed events/jam", "cancelled events/talk", "new events/quiz"}
+	if len(changes) != len(want) {
+		t.Fatalf("diff() = %v, want %v", changes, want)
+	}
+	for i, c := range changes {
+		if got := c.Kind + " " + c.Event.ID; got != want[i] {
+			t.Errorf("change %d = %s, want %s", i, got, want[i])
+		}
+	}
+	if changes[0].Old.Venue != "Chill Room" {
+		t.Errorf("changed booking was at %s before", changes[0].Old.Venue)
+	}
+
+	if changes := sw.diff(Spaces{Space{moved, quiz}}, later); len(changes) != 0 {
+		t.Errorf("unchanged bookings returned changes: %v", changes)
+	}
+}
+
+func TestVenueWatchMessages(t *testing.T) {
+	now := time.Date(2018, 11, 19, 9, 0, 0, 0, utils.SgLocation())
+	old := Event{ID: "events/jam", Name: "Jam", Venue: "Chill Room", Start: now, End: now.Add(time.Hour)}
+	moved := old
+	moved.Venue = "Cinnamon Lounge"
+	watches := []model.VenueWatch{
+		{UserID: 1, Venue: "Chill Room"},
+		{UserID: 1, Venue: "Cinnamon Lounge"},
+		{UserID: 2, Venue: "Cinnamon Lounge"},
+		{UserID: 3, Venue: "Multi-Purpose Sports Hall"},
+	}
+	messages, told := venueWatchMessages(watches, []bookingChange{{Kind: "changed", Event: moved, Old: old}})
+
+	if len(messages) != 2 {
+		t.Fatalf("messages sent to %d users, want 2", len(messages))
+	}
+	if strings.Count(messages[1], "Booking changed") != 1 {
+		t.Errorf("user watching both venues was told %d times", strings.Count(messages[1], "Booking changed"))
+	}
+	if !strings.Contains(messages[2], "at Chill Room)") {
+		t.Errorf("message does not say where the booking was:\n%s", messages[2])
+	}
+	if !told["events/jam"][1] || !told["events/jam"][2] || told["events/jam"][3] {
+		t.Errorf("told = %v, want users 1 and 2", told["events/jam"])
+	}
+}
+
+func TestVenueBookingsKeyboard(t *testing.T) {
+	now := time.Date(2018, 11, 19, 9, 0, 0, 0, utils.SgLocation())
+	space := Space{{ID: "events/started", Name: "Started", Venue: "Chill Room", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}}
+	for i := 0; i < maxReminderButtons+2; i++ {
+		space = append(space, Event{ID: "events/e" + string(rune('a'+i)), Name: "Jam", Venue: "Chill Room", Start: now.Add(time.Duration(i+1) * time.Hour), End: now.Add(time.Duration(i+2) * time.Hour)})
+	}
+	keyboard := venueBookingsKeyboard(Spaces{space}, "Chill Room", nil, true, now)
+
+	rows := keyboard.InlineKeyboard
+	if len(rows) != maxReminderButtons+1 {
+		t.Fatalf("keyboard has %d rows, want %d", len(rows), maxReminderButtons+1)
+	}
+	if rows[0][0].Text != "🔕 Stop watching" || *rows[0][0].CallbackData != "//spaces_watch Chill Room" {
+		t.Errorf("unexpected watch button %q %q", rows[0][0].Text, *rows[0][0].CallbackData)
+	}
+	if *rows[1][0].CallbackData != "//spaces_remind ea" {
+		t.Errorf("first reminder button is %q, want the first booking which has not started", *rows[1][0].CallbackData)
+	}
+	if event, ok := (Spaces{space}).findEvent("ea"); !ok || event.ID != "events/ea" {
+		t.Errorf("findEvent(ea) = %v, %v", event, ok)
+	}
+}
@@ -1,7 +1,11 @@
 package cinnabot
 
 import (
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/usdevs/cinnabot/utils"
 
 	tgbotapi "gopkg.in/telegram-bot-api.v4"
 )
@@ -81,3 +85,64 @@ func TestGetNoEvents(t *testing.T) {
 	mb.On("Send", expectedMsg).Return(nil)
 	cb.Spaces(&mockMsg)
 }
+
+func TestSplitVenueArgs(t *testing.T) {
+	tests := []struct {
+		args   []string
+		venue  string
+		period []string
+	}{
+		{[]string{"chill", "room"}, "chill room", []string{}},
+		{[]string{"chill", "room", "tomorrow"}, "chill room", []string{"tomorrow"}},
+		{[]string{"chill", "room", "19/11", "21/11"}, "chill room", []string{"19/11", "21/11"}},
+		{[]string{"week"}, "week", []string{}},
+		{[]string{"mpsh", "1/1", "2/1", "3/1"}, "mpsh 1/1", []string{"2/1", "3/1"}},
+	}
+	for _, test := range tests {
+		venue, period := splitVenueArgs(test.args)
+		if venue != test.venue || strings.Join(period, " ") != strings.Join(test.period, " ") {
+			t.Errorf("splitVenueArgs(%q) = %q, %q, want %q, %q", test.args, venue, period, test.venue, test.period)
+		}
+	}
+}
+
+func TestMatchVenue(t *testing.T) {
+	venues := []string{"Chill Room", "Cinnamon Lounge", "Multi-Purpose Sports Hall"}
+	if venue, ok := matchVenue(venues, "chill"); !ok || venue != "Chill Room" {
+		t.Errorf("matchVenue(chill) = %q, %v", venue, ok)
+	}
+	if venue, ok := matchVenue(venues, "sports hall"); !ok || venue != "Multi-Purpose Sports Hall" {
+		t.Errorf("matchVenue(sports hall) = %q, %v", venue, ok)
+	}
+	if _, ok := matchVenue(venues, ""); ok {
+		t.Error("matchVenue matched an empty query")
+	}
+}
+
+func TestVenueBookingsMessage(t *testing.T) {
+	loc := utils.SgLocation()
+	now := time.Date(2018, 11, 19, 9, 0, 0, 0, loc)
+	spaces := Spaces{
+		Space{{Name: "Band practice", Venue: "Chill Room", Start: now.Add(time.Hour), End: now.Add(3 * time.Hour)}},
+		Space{{Name: "Talk", Venue: "Cinnamon Lounge", Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}},
+	}
+
+	text, ok := venueBookingsMessage(spaces, "Chill Room", []string{"today"}, now)
+	if !ok || !strings.Contains(text, "Band practice") || strings.Contains(text, "Talk") {
+		t.Errorf("unexpected bookings today:\n%s", text)
+	}
+	text, ok = venueBookingsMessage(spaces, "Chill Room", []string{"tomorrow"}, now)
+	if !ok || !strings.HasSuffix(text, "[No bookings recorded]") {
+		t.Errorf("unexpected bookings tomorrow:\n%s", text)
+	}
+	if _, ok := venueBookingsMessage(spaces, "Chill Room", []string{"1/1/18", "1/3/18"}, now); ok {
+		t.Error("accepted a range longer than 33 days")
+	}
+}
+
+func TestVenueButton(t *testing.T) {
+	button := venueButton("An Extremely Long Venue Name That Does Not Fit In Callback Data")
+	if len(*button.CallbackData) > maxCallbackDataLen {
+		t.Errorf("callback data %q is too long", *button.CallbackData)
+	}
+}
@@ -0,0 +1,157 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StopArrivals is the result of fetching the arrivals at one stop.
+type StopArrivals struct {
+	Code     string
+	Arrivals []Arrival
+	Err      error
+}
+
+// Client fetches arrivals from a Provider, caching the result for each stop for a short time. Concurrent
+// requests for a stop which is not cached share a single upstream request.
+type Client struct {
+	provider Provider
+	ttl      time.Duration // how long arrivals are cached for
+	timeout  time.Duration // deadline for a single upstream request
+	now      func() time.Time
+
+	stopsOnce sync.Once
+	stops     *StopIndex
+	stopsErr  error
+
+	mu       sync.Mutex
+	cached   map[string]cacheEntry
+	inflight map[string]*call
+}
+
+type cacheEntry struct {
+	arrivals []Arrival
+	expiry   time.Time
+}
+
+// call is an upstream request which may be shared by several callers
+type call struct {
+	done     chan struct{}
+	arrivals []Arrival
+	err      error
+}
+
+// NewClient returns a Client which fetches arrivals from the provider.
+func NewClient(provider Provider, ttl, timeout time.Duration) *Client {
+	return &Client{
+		provider: provider,
+		ttl:      ttl,
+		timeout:  timeout,
+		now:      time.Now,
+		cached:   make(map[string]cacheEntry),
+		inflight: make(map[string]*call),
+	}
+}
+
+// index returns the index of the stops served by the provider, which are loaded the first time
+// they are needed. If they cannot be loaded, the index is empty.
+func (c *Client) index() (*StopIndex, error) {
+	c.stopsOnce.Do(func() {
+		stops, err := c.provider.Stops()
+		c.stops, c.stopsErr = NewStopIndex(stops), err
+	})
+	return c.stops, c.stopsErr
+}
+
+// Stops returns all bus stops served by the client's provider. They are only read from the
+// provider once, so Stops can be called at startup to load them and check for errors.
+func (c *Client) Stops() ([]Stop, error) {
+	x, err := c.index()
+	return x.Stops(), err
+}
+
+// Stop returns the stop with the given code.
+func (c *Client) Stop(code string) (Stop, bool) {
+	x, _ := c.index()
+	return x.Stop(code)
+}
+
+// Nearest returns up to n of the stops within radius metres of a point, nearest first.
+func (c *Client) Nearest(lat, lng float64, n int, radius float64) []Stop {
+	x, _ := c.index()
+	return x.Nearest(lat, lng, n, radius)
+}
+
+// Within returns all the stops within radius metres of a point, nearest first.
+func (c *Client) Within(lat, lng float64, radius float64) []Stop {
+	x, _ := c.index()
+	return x.Within(lat, lng, radius)
+}
+
+// Arrivals returns the arrivals at the stop with the given code. An error wrapping ErrUnavailable is
+// returned if the arrivals could not be fetched before ctx is done or the client's timeout passes.
+func (c *Client) Arrivals(ctx context.Context, code string) ([]Arrival, error) {
+	c.mu.Lock()
+	if entry, ok := c.cached[code]; ok && c.now().Before(entry.expiry) {
+		c.mu.Unlock()
+		return entry.arrivals, nil
+	}
+	cl, ok := c.inflight[code]
+	if !ok {
+		cl = &call{done: make(chan struct{})}
+		c.inflight[code] = cl
+		go c.do(code, cl)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.arrivals, cl.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %s: %s", ErrUnavailable, code, ctx.Err())
+	}
+}
+
+// do performs the upstream request for a call. It is not tied to the context of any one caller,
+// as other callers may be waiting on the same request.
+func (c *Client) do(code string, cl *call) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	arrivals, err := c.provider.Arrivals(ctx, code)
+	if err != nil {
+		err = fmt.Errorf("%w: %s: %s", ErrUnavailable, code, err)
+	}
+
+	c.mu.Lock()
+	if err == nil {
+		c.cached[code] = cacheEntry{arrivals: arrivals, expiry: c.now().Add(c.ttl)}
+	}
+	delete(c.inflight, code)
+	c.mu.Unlock()
+
+	cl.arrivals, cl.err = arrivals, err
+	close(cl.done)
+}
+
+// ArrivalsAt concurrently fetches the arrivals at each of the stops. Results are returned in the
+// same order as codes. Stops which do not respond within the client's timeout have an error set.
+func (c *Client) ArrivalsAt(ctx context.Context, codes []string) []StopArrivals {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]StopArrivals, len(codes))
+	var wg sync.WaitGroup
+	for i, code := range codes {
+		wg.Add(1)
+		go func(i int, code string) {
+			defer wg.Done()
+			arrivals, err := c.Arrivals(ctx, code)
+			results[i] = StopArrivals{Code: code, Arrivals: arrivals, Err: err}
+		}(i, code)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,120 @@
+package transit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// funcProvider is a Provider which fetches arrivals using a function
+type funcProvider func(ctx context.Context, code string) ([]Arrival, error)
+
+func (f funcProvider) Stops() ([]Stop, error) { return nil, nil }
+
+func (f funcProvider) Arrivals(ctx context.Context, code string) ([]Arrival, error) {
+	return f(ctx, code)
+}
+
+func TestClientCaches(t *testing.T) {
+	var calls int32
+	fetch := func(ctx context.Context, code string) ([]Arrival, error) {
+		atomic.AddInt32(&calls, 1)
+		return []Arrival{{Service: "D2"}}, nil
+	}
+	c := NewClient(funcProvider(fetch), time.Minute, time.Second)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Arrivals(context.Background(), "COM2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 upstream request while cached, got %d", calls)
+	}
+
+	// cache expires after the ttl
+	now = now.Add(2 * time.Minute)
+	c.Arrivals(context.Background(), "COM2")
+	if calls != 2 {
+		t.Errorf("expected 2 upstream requests after expiry, got %d", calls)
+	}
+}
+
+func TestClientCoalesces(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context, code string) ([]Arrival, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []Arrival{{Service: "D2"}}, nil
+	}
+	c := NewClient(funcProvider(fetch), time.Minute, time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Arrivals(context.Background(), "COM2")
+		}()
+	}
+	// wait for all callers to be waiting on the same request
+	for {
+		c.mu.Lock()
+		_, waiting := c.inflight["COM2"]
+		c.mu.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected concurrent requests to be coalesced into 1, got %d", calls)
+	}
+}
+
+func TestClientArrivalsAtDeadline(t *testing.T) {
+	fetch := func(ctx context.Context, code string) ([]Arrival, error) {
+		switch code {
+		case "SLOW":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		case "BROKEN":
+			return nil, errors.New("bad response")
+		}
+		return []Arrival{{Service: "A1"}}, nil
+	}
+	c := NewClient(funcProvider(fetch), time.Minute, 50*time.Millisecond)
+
+	start := time.Now()
+	results := c.ArrivalsAt(context.Background(), []string{"UTown", "SLOW", "BROKEN"})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ArrivalsAt took %s, expected it to give up after the timeout", elapsed)
+	}
+
+	if results[0].Code != "UTown" || results[0].Err != nil || len(results[0].Arrivals) != 1 {
+		t.Errorf("unexpected result for UTown: %+v", results[0])
+	}
+	for _, r := range results[1:] {
+		if !errors.Is(r.Err, ErrUnavailable) {
+			t.Errorf("expected ErrUnavailable for %s, got %v", r.Code, r.Err)
+		}
+	}
+
+	// failures are not cached
+	c.mu.Lock()
+	_, cached := c.cached["BROKEN"]
+	c.mu.Unlock()
+	if cached {
+		t.Error("failed request should not be cached")
+	}
+}
@@ -0,0 +1,176 @@
+package transit
+
+import (
+	"math"
+	"sort"
+)
+
+// cellSize is the size in degrees of the cells of the grid in StopIndex, about 550 m
+const cellSize = 0.005
+
+// metresPerDegree is the length of one degree of latitude in metres
+const metresPerDegree = 111195
+
+// StopIndex answers queries for stops by code and by location over a fixed set of stops.
+// Stops are bucketed into a grid of cells by their coordinates, so that queries by location
+// only need to look at the stops in nearby cells. It is safe for concurrent use.
+type StopIndex struct {
+	stops  []Stop
+	byCode map[string]int
+	cells  map[cell][]int // indices of the stops in each cell
+
+	minRow, maxRow, minCol, maxCol int // extent of the grid
+}
+
+type cell struct {
+	row, col int
+}
+
+func cellOf(lat, lng float64) cell {
+	return cell{int(math.Floor(lat / cellSize)), int(math.Floor(lng / cellSize))}
+}
+
+// NewStopIndex returns an index of the stops.
+func NewStopIndex(stops []Stop) *StopIndex {
+	x := &StopIndex{
+		stops:  stops,
+		byCode: make(map[string]int, len(stops)),
+		cells:  make(map[cell][]int),
+	}
+	for i, s := range stops {
+		x.byCode[s.Code] = i
+		c := cellOf(s.Latitude, s.Longitude)
+		x.cells[c] = append(x.cells[c], i)
+		if i == 0 || c.row < x.minRow {
+			x.minRow = c.row
+		}
+		if i == 0 || c.row > x.maxRow {
+			x.maxRow = c.row
+		}
+		if i == 0 || c.col < x.minCol {
+			x.minCol = c.col
+		}
+		if i == 0 || c.col > x.maxCol {
+			x.maxCol = c.col
+		}
+	}
+	return x
+}
+
+// Stops returns all the stops in the index.
+func (x *StopIndex) Stops() []Stop {
+	return x.stops
+}
+
+// Stop returns the stop with the given code.
+func (x *StopIndex) Stop(code string) (Stop, bool) {
+	i, ok := x.byCode[code]
+	if !ok {
+		return Stop{}, false
+	}
+	return x.stops[i], true
+}
+
+// candidate is a stop found by a query, with its distance from the point queried
+type candidate struct {
+	index    int
+	distance float64
+}
+
+// byDistance sorts candidates nearest first, breaking ties by their order in the index
+type byDistance []candidate
+
+func (c byDistance) Len() int      { return len(c) }
+func (c byDistance) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byDistance) Less(i, j int) bool {
+	if c[i].distance != c[j].distance {
+		return c[i].distance < c[j].distance
+	}
+	return c[i].index < c[j].index
+}
+
+func (x *StopIndex) toStops(candidates []candidate) []Stop {
+	stops := make([]Stop, len(candidates))
+	for i, c := range candidates {
+		stops[i] = x.stops[c.index]
+	}
+	return stops
+}
+
+// Within returns all the stops within radius metres of a point, nearest first.
+func (x *StopIndex) Within(lat, lng float64, radius float64) []Stop {
+	return x.toStops(x.search(lat, lng, -1, radius))
+}
+
+// Nearest returns up to n of the stops within radius metres of a point, nearest first.
+// radius can be math.Inf(1) to find the nearest stops however far away they are.
+func (x *StopIndex) Nearest(lat, lng float64, n int, radius float64) []Stop {
+	if n <= 0 {
+		return []Stop{}
+	}
+	return x.toStops(x.search(lat, lng, n, radius))
+}
+
+// search looks at the cells in rings of increasing size around the point, until the stops found
+// are nearer than any stop in the cells not yet looked at. n < 0 finds all stops within radius.
+func (x *StopIndex) search(lat, lng float64, n int, radius float64) []candidate {
+	found := make([]candidate, 0)
+	if len(x.stops) == 0 {
+		return found
+	}
+
+	point := Stop{Latitude: lat, Longitude: lng}
+	centre := cellOf(lat, lng)
+	// Cells are narrower from east to west than from north to south, so distances are bounded using
+	// their width at the point. Cells further from the equator are narrower still, but not by enough
+	// to matter within a few degrees of it.
+	cellMetres := cellSize * metresPerDegree * math.Cos(math.Min(math.Abs(lat)+cellSize, 89)*math.Pi/180)
+
+	for r := 0; ; r++ {
+		x.searchRing(point, centre, r, radius, &found)
+		if centre.row-r <= x.minRow && centre.row+r >= x.maxRow && centre.col-r <= x.minCol && centre.col+r >= x.maxCol {
+			break // every cell has been looked at
+		}
+
+		// Every stop not yet looked at is more than r cells away
+		unsearched := float64(r) * cellMetres
+		if unsearched > radius {
+			break
+		}
+		if n >= 0 && len(found) >= n {
+			sort.Sort(byDistance(found))
+			if found[n-1].distance <= unsearched {
+				break
+			}
+		}
+	}
+
+	sort.Sort(byDistance(found))
+	if n >= 0 && len(found) > n {
+		found = found[:n]
+	}
+	return found
+}
+
+// searchRing adds the stops within radius in the cells exactly r cells away from centre to found
+func (x *StopIndex) searchRing(point Stop, centre cell, r int, radius float64, found *[]candidate) {
+	visit := func(c cell) {
+		for _, i := range x.cells[c] {
+			if d := distance(point, x.stops[i]); d <= radius {
+				*found = append(*found, candidate{i, d})
+			}
+		}
+	}
+	if r == 0 {
+		visit(centre)
+		return
+	}
+	for col := centre.col - r; col <= centre.col+r; col++ {
+		visit(cell{centre.row - r, col})
+		visit(cell{centre.row + r, col})
+	}
+	for row := centre.row - r + 1; row < centre.row+r; row++ {
+		visit(cell{row, centre.col - r})
+		visit(cell{row, centre.col + r})
+	}
+}
@@ -0,0 +1,187 @@
+package transit
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestStopIndex(t *testing.T) {
+	stops, err := ReadStops("../main/publicstops.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := NewStopIndex(stops)
+
+	stop, ok := x.Stop("19059")
+	if !ok || stop.Name != "University Town" {
+		t.Errorf("expected 19059 to be University Town, got %v", stop)
+	}
+	if _, ok := x.Stop("00000"); ok {
+		t.Error("expected no stop with code 00000")
+	}
+
+	// Nearest stops to Cinnamon College
+	got := codes(x.Nearest(1.306671, 103.773556, 4, 800))
+	expected := []string{"17099", "19051", "19059", "17091"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := x.Nearest(1.306671, 103.773556, 4, 260); len(got) != 2 {
+		t.Errorf("expected 2 stops within 260 m, got %v", got)
+	}
+	if got := codes(x.Within(1.306671, 103.773556, 300)); !reflect.DeepEqual(got, expected[:3]) {
+		t.Errorf("expected %v within 300 m, got %v", expected[:3], got)
+	}
+	if got := NewStopIndex(nil).Nearest(1.306671, 103.773556, 4, math.Inf(1)); len(got) != 0 {
+		t.Errorf("expected no stops in an empty index, got %v", got)
+	}
+}
+
+// TestStopIndexMatchesLinearSearch checks the index against distances to every stop, at random
+// points in and around Singapore
+func TestStopIndexMatchesLinearSearch(t *testing.T) {
+	stops, err := ReadStops("../main/publicstops.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := NewStopIndex(stops)
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		lat, lng := 1.1+rng.Float64()*0.5, 103.5+rng.Float64()*0.7
+		radius := []float64{300, 1000, 5000, math.Inf(1)}[i%4]
+
+		all := linearSearch(stops, lat, lng, radius)
+		want := all
+		if len(want) > 5 {
+			want = want[:5]
+		}
+		if got := codes(x.Nearest(lat, lng, 5, radius)); !reflect.DeepEqual(got, codes(want)) {
+			t.Fatalf("Nearest(%f, %f, 5, %.0f): expected %v, got %v", lat, lng, radius, codes(want), got)
+		}
+		if radius < 5000 {
+			if got := codes(x.Within(lat, lng, radius)); !reflect.DeepEqual(got, codes(all)) {
+				t.Fatalf("Within(%f, %f, %.0f): expected %v, got %v", lat, lng, radius, codes(all), got)
+			}
+		}
+	}
+}
+
+func linearSearch(stops []Stop, lat, lng, radius float64) []Stop {
+	point := Stop{Latitude: lat, Longitude: lng}
+	found := make([]Stop, 0)
+	for _, s := range stops {
+		if distance(point, s) <= radius {
+			found = append(found, s)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return distance(point, found[i]) < distance(point, found[j]) })
+	return found
+}
+
+func codes(stops []Stop) []string {
+	codes := make([]string, len(stops))
+	for i, s := range stops {
+		codes[i] = s.Code
+	}
+	return codes
+}
+
+// stopHeap is how nearest stops used to be found: every stop was put in a heap ordered by
+// distance on each request, and the nearest few popped off
+type stopHeap struct {
+	stops []Stop
+	point Stop
+}
+
+func (h stopHeap) Len() int { return len(h.stops) }
+func (h stopHeap) Less(i, j int) bool {
+	return distance(h.point, h.stops[i]) < distance(h.point, h.stops[j])
+}
+func (h stopHeap) Swap(i, j int) { h.stops[i], h.stops[j] = h.stops[j], h.stops[i] }
+func (h *stopHeap) Push(x interface{}) {
+	h.stops = append(h.stops, x.(Stop))
+}
+func (h *stopHeap) Pop() interface{} {
+	old := h.stops
+	x := old[len(old)-1]
+	h.stops = old[:len(old)-1]
+	return x
+}
+
+func heapNearest(stops []Stop, lat, lng float64, n int) []Stop {
+	h := &stopHeap{append([]Stop(nil), stops...), Stop{Latitude: lat, Longitude: lng}}
+	heap.Init(h)
+	nearest := make([]Stop, 0, n)
+	for i := 0; i < n && h.Len() > 0; i++ {
+		nearest = append(nearest, heap.Pop(h).(Stop))
+	}
+	return nearest
+}
+
+func benchmarkPoints() [][2]float64 {
+	rng := rand.New(rand.NewSource(1))
+	points := make([][2]float64, 100)
+	for i := range points {
+		points[i] = [2]float64{1.25 + rng.Float64()*0.2, 103.65 + rng.Float64()*0.35}
+	}
+	return points
+}
+
+func BenchmarkNearestHeap(b *testing.B) {
+	stops, err := ReadStops("../main/publicstops.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	points := benchmarkPoints()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		heapNearest(stops, p[0], p[1], 4)
+	}
+}
+
+// BenchmarkNearestHeapFromFile includes reading the stops file, as was done on every request
+func BenchmarkNearestHeapFromFile(b *testing.B) {
+	points := benchmarkPoints()
+	for i := 0; i < b.N; i++ {
+		stops, err := ReadStops("../main/publicstops.json")
+		if err != nil {
+			b.Fatal(err)
+		}
+		p := points[i%len(points)]
+		heapNearest(stops, p[0], p[1], 4)
+	}
+}
+
+func BenchmarkNearestIndex(b *testing.B) {
+	stops, err := ReadStops("../main/publicstops.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	x := NewStopIndex(stops)
+	points := benchmarkPoints()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		x.Nearest(p[0], p[1], 4, 800)
+	}
+}
+
+func BenchmarkNearestIndexUnbounded(b *testing.B) {
+	stops, err := ReadStops("../main/publicstops.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	x := NewStopIndex(stops)
+	points := benchmarkPoints()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := points[i%len(points)]
+		x.Nearest(p[0], p[1], 4, math.Inf(1))
+	}
+}
@@ -0,0 +1,115 @@
+package transit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultLTAURL is the endpoint of the LTA DataMall bus arrival API.
+const DefaultLTAURL = "http://datamall2.mytransport.sg/ltaodataservice/BusArrivalv2"
+
+// LTA is a Provider for public buses, using LTA DataMall.
+type LTA struct {
+	URL        string // defaults to DefaultLTAURL
+	AccountKey string // DataMall API key
+	StopsFile  string // eg. publicstops.json
+}
+
+// Stops implements Provider.
+func (p LTA) Stops() ([]Stop, error) {
+	return ReadStops(p.StopsFile)
+}
+
+// Arrivals implements Provider.
+func (p LTA) Arrivals(ctx context.Context, code string) ([]Arrival, error) {
+	if p.AccountKey == "" {
+		return nil, errors.New("no LTA DataMall account key configured")
+	}
+	base := p.URL
+	if base == "" {
+		base = DefaultLTAURL
+	}
+	req, err := http.NewRequest("GET", base+"?BusStopCode="+url.QueryEscape(code), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("AccountKey", p.AccountKey)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LTA DataMall returned %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseLTA(body, time.Now())
+}
+
+// structs for umarshalling timings from public bus API
+type busTimes struct {
+	Services []service `json:"Services"`
+}
+
+type service struct {
+	ServiceNum string  `json:"ServiceNo"`
+	Next       nextBus `json:"NextBus"`
+	Next2      nextBus `json:"NextBus2"`
+	Next3      nextBus `json:"NextBus3"`
+}
+
+type nextBus struct {
+	EstimatedArrival string `json:"EstimatedArrival"`
+	Load             string `json:"Load"`      // SEA, SDA or LSD
+	Feature          string `json:"Feature"`   // WAB if wheelchair accessible
+	Type             string `json:"Type"`      // SD (single deck), DD (double deck) or BD (bendy)
+	Monitored        int    `json:"Monitored"` // 1 if the arrival time is based on the bus' location
+}
+
+// toBus converts a nextBus into a Bus. Returns false if there is no bus.
+func (nb nextBus) toBus() (Bus, bool) {
+	// EstimatedArrival is empty if there is no bus
+	t, err := time.Parse(time.RFC3339, nb.EstimatedArrival)
+	if err != nil {
+		return Bus{}, false
+	}
+	return Bus{
+		At:                   t,
+		Load:                 nb.Load,
+		WheelchairAccessible: nb.Feature == "WAB",
+		DoubleDecker:         nb.Type == "DD",
+		Monitored:            nb.Monitored == 1,
+	}, true
+}
+
+// parseLTA converts a response from the LTA bus arrival API into Arrivals. LTA gives absolute times, so now is unused.
+func parseLTA(body []byte, now time.Time) ([]Arrival, error) {
+	var bt busTimes
+	if err := json.Unmarshal(body, &bt); err != nil {
+		return nil, err
+	}
+
+	arrivals := make([]Arrival, 0, len(bt.Services))
+	for _, s := range bt.Services {
+		arrival := Arrival{Service: s.ServiceNum, Buses: make([]Bus, 0, 3)}
+		for _, nb := range []nextBus{s.Next, s.Next2, s.Next3} {
+			bus, ok := nb.toBus()
+			if !ok {
+				break
+			}
+			arrival.Buses = append(arrival.Buses, bus)
+		}
+		arrivals = append(arrivals, arrival)
+	}
+	return arrivals, nil
+}
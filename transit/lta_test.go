@@ -0,0 +1,53 @@
+package transit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLTA(t *testing.T) {
+	body := `{"BusStopCode":"19059","Services":[
+		{"ServiceNo":"96",
+			"NextBus":{"EstimatedArrival":"2020-10-15T10:03:30+08:00","Load":"SEA","Feature":"WAB","Type":"DD","Monitored":1},
+			"NextBus2":{"EstimatedArrival":"2020-10-15T10:15:00+08:00","Load":"LSD","Feature":"","Type":"SD","Monitored":0},
+			"NextBus3":{"EstimatedArrival":""}},
+		{"ServiceNo":"151","NextBus":{"EstimatedArrival":""}}]}`
+	now := time.Date(2020, 10, 15, 10, 0, 0, 0, time.FixedZone("+08", 8*60*60))
+
+	arrivals, err := parseLTA([]byte(body), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(arrivals) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(arrivals))
+	}
+	if len(arrivals[0].Buses) != 2 || arrivals[0].Buses[0].MinutesAway(now) != 4 || arrivals[0].Buses[1].MinutesAway(now) != 15 {
+		t.Errorf("expected service 96 to arrive in 4 and 15 mins, got %+v", arrivals[0])
+	}
+	first := Bus{At: arrivals[0].Buses[0].At, Load: SeatsAvailable, WheelchairAccessible: true, DoubleDecker: true, Monitored: true}
+	if arrivals[0].Buses[0] != first {
+		t.Errorf("expected %+v, got %+v", first, arrivals[0].Buses[0])
+	}
+	second := Bus{At: arrivals[0].Buses[1].At, Load: LimitedStanding}
+	if arrivals[0].Buses[1] != second {
+		t.Errorf("expected %+v, got %+v", second, arrivals[0].Buses[1])
+	}
+	if len(arrivals[1].Buses) != 0 {
+		t.Errorf("expected no buses for service 151, got %+v", arrivals[1])
+	}
+}
+
+func TestReadStops(t *testing.T) {
+	stops, err := ReadStops("../main/nusstops.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stops) == 0 {
+		t.Fatal("expected stops to be read")
+	}
+	for _, s := range stops {
+		if s.Code == "COM2" && (s.Latitude < 1.2 || s.Latitude > 1.5 || s.Longitude < 103.6 || s.Longitude > 104.1) {
+			t.Errorf("COM2 has invalid coordinates %f, %f", s.Latitude, s.Longitude)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package transit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultNUSURL is the endpoint of the NUS shuttle service API.
+const DefaultNUSURL = "https://better-nextbus.appspot.com/ShuttleService"
+
+// NUS is a Provider for the NUS internal shuttle buses.
+type NUS struct {
+	URL       string // defaults to DefaultNUSURL
+	StopsFile string // eg. nusstops.json
+}
+
+// Stops implements Provider.
+func (p NUS) Stops() ([]Stop, error) {
+	return ReadStops(p.StopsFile)
+}
+
+// Arrivals implements Provider.
+func (p NUS) Arrivals(ctx context.Context, code string) ([]Arrival, error) {
+	base := p.URL
+	if base == "" {
+		base = DefaultNUSURL
+	}
+	req, err := http.NewRequest("GET", base+"?busstopname="+url.QueryEscape(code), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("NUS shuttle API returned %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseNUS(body, time.Now())
+}
+
+// structs for umarshalling timings from NUS bus API
+type nusResponse struct {
+	Result nusServiceResult `json:"ShuttleServiceResult"`
+}
+
+type nusServiceResult struct {
+	Shuttles []nusShuttle `json:"shuttles"`
+}
+
+type nusShuttle struct {
+	ArrivalTime     string `json:"arrivalTime"`
+	NextArrivalTime string `json:"nextArrivalTime"`
+	Name            string `json:"name"`
+}
+
+// parseNUS converts a response from the NUS shuttle API into Arrivals, relative to the time now.
+func parseNUS(body []byte, now time.Time) ([]Arrival, error) {
+	var nr nusResponse
+	if err := json.Unmarshal(body, &nr); err != nil {
+		return nil, err
+	}
+
+	arrivals := make([]Arrival, 0, len(nr.Result.Shuttles))
+	for _, s := range nr.Result.Shuttles {
+		arrival := Arrival{Service: s.Name, Buses: make([]Bus, 0, 2)}
+		for _, t := range []string{s.ArrivalTime, s.NextArrivalTime} {
+			bus, ok := parseNUSTime(t, now)
+			if !ok {
+				// "-" means there are no more buses
+				break
+			}
+			arrival.Buses = append(arrival.Buses, bus)
+		}
+		arrivals = append(arrivals, arrival)
+	}
+	return arrivals, nil
+}
+
+// parseNUSTime parses an arrival time given by the NUS API, which is either "Arr", "-" or a number of minutes.
+func parseNUSTime(t string, now time.Time) (Bus, bool) {
+	if t == "Arr" {
+		return Bus{At: now}, true
+	}
+	mins, err := strconv.Atoi(t)
+	if err != nil {
+		return Bus{}, false
+	}
+	return Bus{At: now.Add(time.Duration(mins) * time.Minute)}, true
+}
@@ -0,0 +1,40 @@
+package transit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNUS(t *testing.T) {
+	body := `{"ShuttleServiceResult":{"shuttles":[
+		{"name":"A1","arrivalTime":"Arr","nextArrivalTime":"7"},
+		{"name":"D2","arrivalTime":"3","nextArrivalTime":"-"},
+		{"name":"BTC1","arrivalTime":"-","nextArrivalTime":"-"}]}}`
+	now := time.Now()
+
+	arrivals, err := parseNUS([]byte(body), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(arrivals) != 3 {
+		t.Fatalf("expected 3 services, got %d", len(arrivals))
+	}
+
+	expected := map[string][]int{"A1": {0, 7}, "D2": {3}, "BTC1": {}}
+	for _, a := range arrivals {
+		mins := expected[a.Service]
+		if len(a.Buses) != len(mins) {
+			t.Errorf("%s: expected %d buses, got %d", a.Service, len(mins), len(a.Buses))
+			continue
+		}
+		for i, b := range a.Buses {
+			if b.MinutesAway(now) != mins[i] {
+				t.Errorf("%s: expected bus %d in %d mins, got %d", a.Service, i, mins[i], b.MinutesAway(now))
+			}
+		}
+	}
+
+	if _, err := parseNUS([]byte("<html>"), now); err == nil {
+		t.Error("expected error for invalid response")
+	}
+}
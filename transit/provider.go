@@ -0,0 +1,59 @@
+package transit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// Stop is a bus stop.
+type Stop struct {
+	Code      string
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+// Provider is a source of bus stops and arrival timings, eg. the NUS shuttle API or LTA DataMall.
+type Provider interface {
+	// Stops returns all bus stops served by the provider.
+	Stops() ([]Stop, error)
+	// Arrivals returns the arrivals at the stop with the given code.
+	Arrivals(ctx context.Context, code string) ([]Arrival, error)
+}
+
+// stopJSON is the format of a bus stop in the stop files (eg. nusstops.json, publicstops.json)
+type stopJSON struct {
+	Code      string `json:"no"`
+	Latitude  string `json:"lat"`
+	Longitude string `json:"lng"`
+	Name      string `json:"name"`
+}
+
+// ReadStops reads a list of bus stops from a JSON file.
+func ReadStops(path string) ([]Stop, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw []stopJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	stops := make([]Stop, 0, len(raw))
+	for _, s := range raw {
+		lat, err := strconv.ParseFloat(s.Latitude, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: stop %s has invalid latitude %q", path, s.Code, s.Latitude)
+		}
+		lng, err := strconv.ParseFloat(s.Longitude, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: stop %s has invalid longitude %q", path, s.Code, s.Longitude)
+		}
+		stops = append(stops, Stop{Code: s.Code, Name: s.Name, Latitude: lat, Longitude: lng})
+	}
+	return stops, nil
+}
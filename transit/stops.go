@@ -0,0 +1,260 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/usdevs/cinnabot/utils"
+)
+
+// DefaultLTAStopsURL is the endpoint of the LTA DataMall bus stops API.
+const DefaultLTAStopsURL = "http://datamall2.mytransport.sg/ltaodataservice/BusStops"
+
+// ltaPageSize is the number of records returned in each page by LTA DataMall
+const ltaPageSize = 500
+
+// Bounds of Singapore, for validating the coordinates of stops. The LTA dataset includes the
+// stops of cross-border services in Johor Bahru, so the northern bound is slightly beyond Singapore.
+const (
+	minLatitude  = 1.15
+	maxLatitude  = 1.52
+	minLongitude = 103.55
+	maxLongitude = 104.1
+)
+
+// PublicStopCode matches the codes of public bus stops, which are 5 digits. A few stops
+// have a letter in place of the first digit, eg. N4401.
+var PublicStopCode = regexp.MustCompile(`^[0-9A-Z]\d{4}$`)
+
+// ltaStops is a page of the LTA DataMall bus stops API
+type ltaStops struct {
+	Value []struct {
+		BusStopCode string  `json:"BusStopCode"`
+		RoadName    string  `json:"RoadName"`
+		Description string  `json:"Description"`
+		Latitude    float64 `json:"Latitude"`
+		Longitude   float64 `json:"Longitude"`
+	} `json:"value"`
+}
+
+// ParseLTAStops parses a response from the LTA DataMall bus stops API. A local dump of all
+// the stops in the same format can also be parsed.
+func ParseLTAStops(body []byte) ([]Stop, error) {
+	var page ltaStops
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+	stops := make([]Stop, 0, len(page.Value))
+	for _, s := range page.Value {
+		stops = append(stops, Stop{Code: s.BusStopCode, Name: s.Description, Latitude: s.Latitude, Longitude: s.Longitude})
+	}
+	return stops, nil
+}
+
+// FetchLTAStops fetches every public bus stop from LTA DataMall, one page at a time.
+// url defaults to DefaultLTAStopsURL if empty.
+func FetchLTAStops(ctx context.Context, url, accountKey string) ([]Stop, error) {
+	if url == "" {
+		url = DefaultLTAStopsURL
+	}
+	stops := make([]Stop, 0)
+	for skip := 0; ; skip += ltaPageSize {
+		req, err := http.NewRequest("GET", url+"?$skip="+strconv.Itoa(skip), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("AccountKey", accountKey)
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("LTA DataMall returned %s", resp.Status)
+		}
+
+		page, err := ParseLTAStops(body)
+		if err != nil {
+			return nil, fmt.Errorf("page at %d: %w", skip, err)
+		}
+		stops = append(stops, page...)
+		if len(page) < ltaPageSize {
+			return stops, nil
+		}
+	}
+}
+
+// nusStops is the format of the NUS shuttle bus stops API
+type nusStops struct {
+	Result struct {
+		Stops []struct {
+			Name      string  `json:"name"`    // used as the code of the stop
+			Caption   string  `json:"caption"` // display name
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"busstops"`
+	} `json:"BusStopsResult"`
+}
+
+// ParseNUSStops parses a dump of the NUS shuttle bus stops API.
+func ParseNUSStops(body []byte) ([]Stop, error) {
+	var raw nusStops
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	stops := make([]Stop, 0, len(raw.Result.Stops))
+	for _, s := range raw.Result.Stops {
+		stops = append(stops, Stop{Code: s.Name, Name: s.Caption, Latitude: s.Latitude, Longitude: s.Longitude})
+	}
+	return stops, nil
+}
+
+// ValidateStops checks that every stop has a unique code, a name, and coordinates in Singapore.
+// If code is not nil, every code must also match it.
+func ValidateStops(stops []Stop, code *regexp.Regexp) []error {
+	errs := make([]error, 0)
+	seen := make(map[string]bool)
+	for _, s := range stops {
+		switch {
+		case s.Code == "":
+			errs = append(errs, fmt.Errorf("stop %q has no code", s.Name))
+		case code != nil && !code.MatchString(s.Code):
+			errs = append(errs, fmt.Errorf("stop %s has an invalid code", s.Code))
+		case seen[s.Code]:
+			errs = append(errs, fmt.Errorf("stop %s appears more than once", s.Code))
+		}
+		seen[s.Code] = true
+
+		if s.Name == "" {
+			errs = append(errs, fmt.Errorf("stop %s has no name", s.Code))
+		}
+		if s.Latitude < minLatitude || s.Latitude > maxLatitude || s.Longitude < minLongitude || s.Longitude > maxLongitude {
+			errs = append(errs, fmt.Errorf("stop %s has coordinates outside Singapore (%f, %f)", s.Code, s.Latitude, s.Longitude))
+		}
+	}
+	return errs
+}
+
+// movedDistance is how far in metres a stop must move to be reported as changed
+const movedDistance = 20
+
+// StopChange is a stop whose name or location has changed.
+type StopChange struct {
+	Old, New Stop
+}
+
+// StopsDiff is the difference between two lists of stops.
+type StopsDiff struct {
+	Added   []Stop
+	Removed []Stop
+	Changed []StopChange
+}
+
+// Empty returns whether the two lists of stops are the same.
+func (d StopsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (d StopsDiff) String() string {
+	var b bytes.Buffer
+	for _, s := range d.Added {
+		fmt.Fprintf(&b, "+ %s %s\n", s.Code, s.Name)
+	}
+	for _, s := range d.Removed {
+		fmt.Fprintf(&b, "- %s %s\n", s.Code, s.Name)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "~ %s %s", c.Old.Code, c.Old.Name)
+		if c.Old.Name != c.New.Name {
+			fmt.Fprintf(&b, " -> %s", c.New.Name)
+		}
+		if moved := distance(c.Old, c.New); moved >= movedDistance {
+			fmt.Fprintf(&b, " (moved %.0f m)", moved)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+	return b.String()
+}
+
+// DiffStops compares two lists of stops by their codes. Stops which moved less than a few metres
+// are not reported as changed. Each part of the diff is sorted by code.
+func DiffStops(old, new []Stop) StopsDiff {
+	oldByCode := make(map[string]Stop)
+	for _, s := range old {
+		oldByCode[s.Code] = s
+	}
+	newByCode := make(map[string]Stop)
+	for _, s := range new {
+		newByCode[s.Code] = s
+	}
+
+	var diff StopsDiff
+	for _, s := range new {
+		o, ok := oldByCode[s.Code]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, s)
+		case o.Name != s.Name || distance(o, s) >= movedDistance:
+			diff.Changed = append(diff.Changed, StopChange{Old: o, New: s})
+		}
+	}
+	for _, s := range old {
+		if _, ok := newByCode[s.Code]; !ok {
+			diff.Removed = append(diff.Removed, s)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Code < diff.Added[j].Code })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Code < diff.Removed[j].Code })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Old.Code < diff.Changed[j].Old.Code })
+	return diff
+}
+
+// distance returns the distance between two stops in metres
+func distance(a, b Stop) float64 {
+	return utils.Haversine(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+}
+
+// WriteStops writes a list of bus stops to a JSON file in the format read by ReadStops,
+// sorted by code with one stop per line.
+func WriteStops(path string, stops []Stop) error {
+	sorted := append([]Stop(nil), stops...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Code < sorted[j].Code })
+
+	var b, line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	enc.SetEscapeHTML(false) // keep names like "Blk 1 & 2" readable
+	b.WriteString("[\n")
+	for i, s := range sorted {
+		line.Reset()
+		err := enc.Encode(stopJSON{
+			Code:      s.Code,
+			Latitude:  strconv.FormatFloat(s.Latitude, 'f', -1, 64),
+			Longitude: strconv.FormatFloat(s.Longitude, 'f', -1, 64),
+			Name:      s.Name,
+		})
+		if err != nil {
+			return err
+		}
+		b.WriteString("  ")
+		b.Write(bytes.TrimSuffix(line.Bytes(), []byte("\n")))
+		if i < len(sorted)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	return ioutil.WriteFile(path, b.Bytes(), 0644)
+}
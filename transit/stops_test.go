@@ -0,0 +1,139 @@
+package transit
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateStops(t *testing.T) {
+	stops := []Stop{
+		{Code: "19059", Name: "University Town", Latitude: 1.2975, Longitude: 103.7735},
+		{Code: "19059", Name: "Duplicate", Latitude: 1.2975, Longitude: 103.7735},
+		{Code: "1905", Name: "Short code", Latitude: 1.2975, Longitude: 103.7735},
+		{Code: "19051", Name: "", Latitude: 1.2975, Longitude: 103.7735},
+		{Code: "19052", Name: "Null Island", Latitude: 0, Longitude: 0},
+	}
+	errs := ValidateStops(stops, PublicStopCode)
+	if len(errs) != 4 {
+		t.Errorf("expected 4 errors, got %v", errs)
+	}
+
+	for _, file := range []string{"../main/publicstops.json", "../main/nusstops.json"} {
+		stops, err := ReadStops(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := PublicStopCode
+		if strings.Contains(file, "nus") {
+			code = nil
+		}
+		if errs := ValidateStops(stops, code); len(errs) > 0 {
+			t.Errorf("%s: expected stops to be valid, got %v", file, errs)
+		}
+	}
+}
+
+func TestDiffStops(t *testing.T) {
+	old := []Stop{
+		{Code: "1", Name: "Same", Latitude: 1.3, Longitude: 103.8},
+		{Code: "2", Name: "Old name", Latitude: 1.3, Longitude: 103.8},
+		{Code: "3", Name: "Moved", Latitude: 1.3, Longitude: 103.8},
+		{Code: "4", Name: "Removed", Latitude: 1.3, Longitude: 103.8},
+	}
+	new := []Stop{
+		{Code: "5", Name: "Added", Latitude: 1.3, Longitude: 103.8},
+		{Code: "1", Name: "Same", Latitude: 1.30001, Longitude: 103.8}, // about 1 m away
+		{Code: "2", Name: "New name", Latitude: 1.3, Longitude: 103.8},
+		{Code: "3", Name: "Moved", Latitude: 1.301, Longitude: 103.8},
+	}
+	diff := DiffStops(old, new)
+	expected := "+ 5 Added\n- 4 Removed\n~ 2 Old name -> New name\n~ 3 Moved (moved 111 m)\n1 added, 1 removed, 2 changed\n"
+	if diff.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, diff)
+	}
+	if !DiffStops(old, old).Empty() {
+		t.Error("expected no difference between the same stops")
+	}
+}
+
+func TestWriteStops(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stops := []Stop{
+		{Code: "19059", Name: "University Town", Latitude: 1.29738, Longitude: 103.77352},
+		{Code: "10009", Name: "Blk 1 & 2", Latitude: 1.2821, Longitude: 103.8172},
+	}
+	path := filepath.Join(dir, "stops.json")
+	if err := WriteStops(path, stops); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	expected := "[\n" +
+		`  {"no":"10009","lat":"1.2821","lng":"103.8172","name":"Blk 1 & 2"},` + "\n" +
+		`  {"no":"19059","lat":"1.29738","lng":"103.77352","name":"University Town"}` + "\n" +
+		"]\n"
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	read, err := ReadStops(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, []Stop{stops[1], stops[0]}) {
+		t.Errorf("expected stops to be read back sorted by code, got %v", read)
+	}
+}
+
+func TestFetchLTAStops(t *testing.T) {
+	// Serve 1234 stops, 500 per page
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("AccountKey") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		skip, _ := strconv.Atoi(r.URL.Query().Get("$skip"))
+		records := make([]string, 0)
+		for i := skip; i < 1234 && i < skip+ltaPageSize; i++ {
+			records = append(records, `{"BusStopCode":"`+strconv.Itoa(10000+i)+`","Description":"Stop","Latitude":1.3,"Longitude":103.8}`)
+		}
+		w.Write([]byte(`{"value":[` + strings.Join(records, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	stops, err := FetchLTAStops(context.Background(), server.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stops) != 1234 || stops[1233].Code != "11233" {
+		t.Errorf("expected 1234 stops, got %d", len(stops))
+	}
+
+	if _, err := FetchLTAStops(context.Background(), server.URL, "wrong"); err == nil {
+		t.Error("expected an error with the wrong account key")
+	}
+}
+
+func TestParseNUSStops(t *testing.T) {
+	body := `{"BusStopsResult":{"busstops":[{"caption":"AS5","name":"AS7","latitude":1.2935,"longitude":103.7718}]}}`
+	stops, err := ParseNUSStops([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Stop{{Code: "AS7", Name: "AS5", Latitude: 1.2935, Longitude: 103.7718}}
+	if !reflect.DeepEqual(stops, expected) {
+		t.Errorf("expected %v, got %v", expected, stops)
+	}
+}
@@ -0,0 +1,48 @@
+// Package transit fetches bus arrival timings.
+//
+// Client wraps an upstream API with short-lived per-stop caching, coalescing of concurrent
+// requests for the same stop, and concurrent fetching of several stops under a deadline.
+//
+// StopIndex finds the stops nearest to a location using a grid over their coordinates.
+package transit
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrUnavailable is returned when the timings for a stop cannot be fetched.
+var ErrUnavailable = errors.New("timings unavailable")
+
+// Arrival contains the upcoming buses of a single service at a stop.
+type Arrival struct {
+	Service string
+	Buses   []Bus // soonest first, empty if the service is not operating
+}
+
+// Crowd levels of a bus, as given by LTA DataMall
+const (
+	SeatsAvailable    = "SEA"
+	StandingAvailable = "SDA"
+	LimitedStanding   = "LSD"
+)
+
+// Bus is a single upcoming bus. Only At is known for NUS shuttle buses.
+type Bus struct {
+	At                   time.Time // estimated time of arrival
+	Load                 string    // crowd level, one of SeatsAvailable, StandingAvailable or LimitedStanding
+	WheelchairAccessible bool
+	DoubleDecker         bool
+	Monitored            bool // whether At is based on the bus' live location, rather than its schedule
+}
+
+// MinutesAway returns the number of whole minutes (rounded up) until the bus arrives.
+// Buses which are arriving or have arrived are 0 minutes away.
+func (b Bus) MinutesAway(now time.Time) int {
+	mins := int(math.Ceil(b.At.Sub(now).Minutes()))
+	if mins < 0 {
+		return 0
+	}
+	return mins
+}
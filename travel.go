@@ -1,101 +1,66 @@
 package cinnabot
 
 import (
-	"container/heap"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math"
-	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/usdevs/cinnabot/transit"
+	"github.com/usdevs/cinnabot/utils"
 	tgbotapi "gopkg.in/telegram-bot-api.v4"
 )
 
-// busStopHeap is used for GPS location based queries
-type busStopHeap struct {
-	busStopList []busStop
-	location    tgbotapi.Location
+// distanceBetween2 returns the distance in metres between a location and a stop
+func distanceBetween2(Loc1 tgbotapi.Location, Loc2 transit.Stop) float64 {
+	return utils.Haversine(Loc1.Latitude, Loc1.Longitude, Loc2.Latitude, Loc2.Longitude)
 }
 
-func (h busStopHeap) Len() int {
-	return len(h.busStopList)
-}
-
-func (h busStopHeap) Less(i, j int) bool {
-	return distanceBetween2(h.location, h.busStopList[i]) < distanceBetween2(h.location, h.busStopList[j])
-}
+// walkingSpeed is the typical walking speed in metres per minute (4.8 km/h)
+const walkingSpeed = 80
 
-func (h busStopHeap) Swap(i, j int) {
-	h.busStopList[i], h.busStopList[j] = h.busStopList[j], h.busStopList[i]
-}
-
-func (h *busStopHeap) Push(x interface{}) {
-	h.busStopList = append(h.busStopList, x.(busStop))
+// formatDistance formats the distance to a stop with an estimate of the time taken to walk there,
+// eg. "120 m · ~2 min walk"
+func formatDistance(metres float64) string {
+	walk := int(math.Ceil(metres / walkingSpeed))
+	if walk < 1 {
+		walk = 1
+	}
+	if metres >= 1000 {
+		return fmt.Sprintf("%.1f km · ~%d min walk", metres/1000, walk)
+	}
+	return fmt.Sprintf("%d m · ~%d min walk", int(math.Round(metres/10))*10, walk)
 }
 
-func (h *busStopHeap) Pop() interface{} {
-	oldh := h.busStopList
-	n := len(oldh)
-	x := oldh[n-1]
-	h.busStopList = oldh[0 : n-1]
-
-	return x
+// stopHeading formats the name of a stop, followed by its distance if it is not empty
+func stopHeading(name, distance string) string {
+	if distance == "" {
+		return "*" + name + "*"
+	}
+	return "*" + name + "* · " + distance
 }
 
-func distanceBetween2(Loc1 tgbotapi.Location, Loc2 busStop) float64 {
-
-	loc2Lat, _ := strconv.ParseFloat(Loc2.Latitude, 32)
-	loc2Lon, _ := strconv.ParseFloat(Loc2.Longitude, 32)
-
-	x := math.Pow(Loc1.Latitude-loc2Lat, 2)
-	y := math.Pow(Loc1.Longitude-loc2Lon, 2)
-	return x + y
+// nearestStops returns up to n of the stops served by the client which are nearest to loc,
+// leaving out those more than radius metres away
+func nearestStops(client *transit.Client, loc tgbotapi.Location, n int, radius float64) []transit.Stop {
+	return client.Nearest(loc.Latitude, loc.Longitude, n, radius)
 }
 
-// busStop models a public / nus bus stop (from the JSON file)
-type busStop struct {
-	BusStopNumber string `json:"no"`
-	Latitude      string `json:"lat"`
-	Longitude     string `json:"lng"`
-	BusStopName   string `json:"name"`
+// stopCodes returns the codes of the stops
+func stopCodes(stops []transit.Stop) []string {
+	codes := make([]string, len(stops))
+	for i, stop := range stops {
+		codes[i] = stop.Code
+	}
+	return codes
 }
 
 //// NUS BUSES
 
-// structs for umarshalling timings from NUS bus API
-type response struct {
-	Result serviceResult `json:"ShuttleServiceResult"`
-}
-
-type serviceResult struct {
-	Shuttles []shuttle `json:"shuttles"`
-}
-
-type shuttle struct {
-	ArrivalTime     string `json:"arrivalTime"`
-	NextArrivalTime string `json:"nextArrivalTime"`
-	Name            string `json:"name"`
-}
-
-//makeNUSHeap returns a heap for NUS Bus timings
-func makeNUSHeap(loc tgbotapi.Location) busStopHeap {
-	responseData, err := ioutil.ReadFile("nusstops.json")
-	if err != nil {
-		log.Print(err)
-	}
-	points := []busStop{}
-	if err := json.Unmarshal(responseData, &points); err != nil {
-		log.Print(err)
-	}
-	BSH := busStopHeap{points, loc}
-	heap.Init(&BSH)
-	return BSH
-}
-
 // maps user arguments to a key recognised by the nusBusStopLocations map
 var aliases = map[string]string{
 	"kr":    "kr-mrt",
@@ -119,7 +84,7 @@ var nusBusStopLocations = map[string][]string{
 }
 
 // Get a list of bus stop codes from a location code (for button-based query)
-func nusBusResponse_Buttons(code string) (string, bool) {
+func nusBusResponse_Buttons(client *transit.Client, code string, now time.Time) (string, bool) {
 	code = strings.ToLower(code)
 	responseString := ""
 	locs, ok := nusBusStopLocations[code]
@@ -127,162 +92,225 @@ func nusBusResponse_Buttons(code string) (string, bool) {
 		// Format response with timings for bus stop codes
 		lines := make([]string, 0)
 		lines = append(lines, "🤖: Here are the bus timings")
-		for _, loc := range locs {
-			lines = append(lines, getNUSBusTimings(loc, loc))
+		for _, result := range client.ArrivalsAt(context.Background(), locs) {
+			lines = append(lines, formatNUSBusTimings(result.Code, "", result, now))
 		}
-		lines = append(lines, "Last updated: "+time.Now().Format(time.RFC822))
+		lines = append(lines, "Last updated: "+now.Format(time.RFC822))
 		responseString = strings.Join(lines, "\n")
 	}
 	return responseString, ok
 }
 
 // for location-based query
-func nusBusResponse_Location(BSH *busStopHeap) string {
+func nusBusResponse_Location(client *transit.Client, loc tgbotapi.Location, stops []transit.Stop, now time.Time) string {
 	lines := make([]string, 0)
 	lines = append(lines, "🤖: Here are the bus timings")
-	for i := 0; i < 3; i++ {
-		stop := heap.Pop(BSH).(busStop)
-		lines = append(lines, getNUSBusTimings(stop.BusStopNumber, stop.BusStopName))
+	for i, result := range client.ArrivalsAt(context.Background(), stopCodes(stops)) {
+		lines = append(lines, formatNUSBusTimings(stops[i].Name, formatDistance(distanceBetween2(loc, stops[i])), result, now))
 	}
-	lines = append(lines, "Last updated: "+time.Now().Format(time.RFC822))
+	lines = append(lines, "Last updated: "+now.Format(time.RFC822))
 	return strings.Join(lines, "\n")
 }
 
-// fetch NUS bus timings from web API
-func getNUSBusTimings(code, displayName string) string {
-	returnMessage := "*" + displayName + "*\n"
-	resp, _ := http.Get("https://better-nextbus.appspot.com/ShuttleService?busstopname=" + code)
-
-	responseData, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Print(err)
-	}
-	var bt response
-	if err := json.Unmarshal(responseData, &bt); err != nil {
-		log.Print(err)
+// formatNUSBusTimings formats the NUS bus timings at a stop. The distance to the stop is left out if empty.
+func formatNUSBusTimings(displayName, distance string, result transit.StopArrivals, now time.Time) string {
+	returnMessage := stopHeading(displayName, distance) + "\n"
+	if result.Err != nil {
+		log.Print(result.Err)
+		return returnMessage + "Timings unavailable\n"
 	}
 
-	for j := 0; j < len(bt.Result.Shuttles); j++ {
-		arrivalTime := bt.Result.Shuttles[j].ArrivalTime
-		nextArrivalTime := bt.Result.Shuttles[j].NextArrivalTime
-
-		if arrivalTime == "-" {
-			returnMessage += "🛑" + bt.Result.Shuttles[j].Name + " : - mins\n"
+	for _, arrival := range result.Arrivals {
+		if len(arrival.Buses) == 0 {
+			returnMessage += "🛑" + arrival.Service + " : - mins\n"
 			continue
-		} else if arrivalTime == "1" {
-			returnMessage += "🚍" + bt.Result.Shuttles[j].Name + " : " + arrivalTime + " min, " + nextArrivalTime + " mins\n"
-		} else if arrivalTime == "Arr" {
-			returnMessage += "🚍" + bt.Result.Shuttles[j].Name + " : " + arrivalTime + ", " + nextArrivalTime + " mins\n"
-		} else {
-			returnMessage += "🚍" + bt.Result.Shuttles[j].Name + " : " + arrivalTime + " mins, " + nextArrivalTime + " mins\n"
 		}
+		returnMessage += "🚍" + arrival.Service + " : " + formatNUSBuses(arrival.Buses, now) + "\n"
 	}
 	return returnMessage
 }
 
+// formatNUSBuses formats the times until the next two buses of a service, eg. "Arr, 7 mins"
+func formatNUSBuses(buses []transit.Bus, now time.Time) string {
+	var arrivalTime string
+	switch mins := buses[0].MinutesAway(now); mins {
+	case 0:
+		arrivalTime = "Arr"
+	case 1:
+		arrivalTime = "1 min"
+	default:
+		arrivalTime = strconv.Itoa(mins) + " mins"
+	}
+	nextArrivalTime := "-"
+	if len(buses) > 1 {
+		nextArrivalTime = strconv.Itoa(buses[1].MinutesAway(now))
+	}
+	return arrivalTime + ", " + nextArrivalTime + " mins"
+}
+
 //// PUBLIC BUSES
 
-// structs for umarshalling timings from public bus API
-type busTimes struct {
-	Services []service `json:"Services"`
+//publicBusResponse returns the bus timings at the given stops, and their distances from loc if it is not nil
+func publicBusResponse(client *transit.Client, loc *tgbotapi.Location, stops []transit.Stop, now time.Time) string {
+	lines := make([]string, 0)
+	lines = append(lines, "🤖: Here are the bus timings")
+	//Get data for the bus stops concurrently
+	for i, result := range client.ArrivalsAt(context.Background(), stopCodes(stops)) {
+		distance := ""
+		if loc != nil {
+			distance = formatDistance(distanceBetween2(*loc, stops[i]))
+		}
+		lines = append(lines, formatPublicBusTimings(stops[i].Name, distance, result, now))
+	}
+	lines = append(lines, publicBusLegend)
+	lines = append(lines, "Last updated: "+now.Format(time.RFC822))
+	return strings.Join(lines, "\n")
 }
 
-type service struct {
-	ServiceNum string  `json:"ServiceNo"`
-	Next       nextBus `json:"NextBus"`
+// formatPublicBusTimings formats the public bus timings at a stop. The distance to the stop is left out if empty.
+func formatPublicBusTimings(displayName, distance string, result transit.StopArrivals, now time.Time) string {
+	lines := []string{stopHeading(displayName, distance)}
+	if result.Err != nil {
+		log.Print(result.Err)
+		lines = append(lines, "Timings unavailable")
+	}
+	for _, arrival := range result.Arrivals {
+		if len(arrival.Buses) == 0 {
+			lines = append(lines, "🛑Bus "+arrival.Service+" : not in operation")
+			continue
+		}
+		buses := make([]string, 0, len(arrival.Buses))
+		for _, bus := range arrival.Buses {
+			buses = append(buses, formatPublicBus(bus, now))
+		}
+		lines = append(lines, "🚍Bus "+arrival.Service+" : "+strings.Join(buses, ", "))
+	}
+	lines = append(lines, "")
+	return strings.Join(lines, "\n")
 }
 
-type nextBus struct {
-	EstimatedArrival string `json:"EstimatedArrival"`
+var loadIcons = map[string]string{
+	transit.SeatsAvailable:    "🟢",
+	transit.StandingAvailable: "🟡",
+	transit.LimitedStanding:   "🔴",
 }
 
-func makeHeap(loc tgbotapi.Location) busStopHeap {
-	//resp, _ := http.Get("https://busrouter.sg/data/2/bus-stops.json")
-	responseData, _ := ioutil.ReadFile("publicstops.json")
-	points := []busStop{}
-	if err := json.Unmarshal(responseData, &points); err != nil {
-		log.Print(err)
+const publicBusLegend = "🟢 seats 🟡 standing 🔴 limited standing\n♿ wheelchair accessible · DD double decker · \\* estimated from schedule\n"
+
+// formatPublicBus formats a single public bus, eg. "4 min 🟢 ♿ DD". Arrival times which are not monitored are marked with an asterisk.
+func formatPublicBus(bus transit.Bus, now time.Time) string {
+	parts := make([]string, 0, 4)
+	mins := strconv.Itoa(bus.MinutesAway(now)) + " min"
+	if !bus.Monitored {
+		mins += "\\*"
+	}
+	parts = append(parts, mins)
+	if icon, ok := loadIcons[bus.Load]; ok {
+		parts = append(parts, icon)
 	}
-	BSH := busStopHeap{points, loc}
-	heap.Init(&BSH)
-	return BSH
+	if bus.WheelchairAccessible {
+		parts = append(parts, "♿")
+	}
+	if bus.DoubleDecker {
+		parts = append(parts, "DD")
+	}
+	return strings.Join(parts, " ")
 }
 
-//busTimingResponse returns string given a busstopheap
-func publicBusResponse(BSH *busStopHeap) string {
-	lines := make([]string, 0)
-	lines = append(lines, "🤖: Here are the bus timings")
-	//Iteratively get data for each closest bus stop.
-	for i := 0; i < 4; i++ {
-
-		busStop := heap.Pop(BSH).(busStop)
-
-		busStopCode := busStop.BusStopNumber
+//// REFRESH BUTTONS
 
-		lines = append(lines, "*"+busStop.BusStopName+"*")
+func makeNUSBusKeyboard_Buttons(code string) tgbotapi.InlineKeyboardMarkup {
+	stops := make([]transit.Stop, 0)
+	for _, stop := range nusBusStopLocations[code] {
+		stops = append(stops, transit.Stop{Code: stop, Name: stop})
+	}
+	refresh := "//nusbus_refresh " + code
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		makeRefreshRow(refresh),
+	}
+	rows = append(rows, makeStarButtons("nus", stops)...)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
 
-		//Send request to my transport sg for bus timing data
-		client := &http.Client{}
+func makeNUSBusKeyboard_Location(loc tgbotapi.Location, stops []transit.Stop) tgbotapi.InlineKeyboardMarkup {
+	refresh := fmt.Sprintf("//nusbus_loc_refresh %f %f", loc.Longitude, loc.Latitude)
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		makeRefreshRow(refresh),
+	}
+	rows = append(rows, makeStarButtons("nus", stops)...)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
 
-		req, _ := http.NewRequest("GET",
-			"http://datamall2.mytransport.sg/ltaodataservice/BusArrivalv2?BusStopCode="+busStopCode, nil)
-		req.Header.Set("AccountKey", "l88uTu9nRjSO6VYUUwilWg==")
+func makePublicBusKeyboard(loc tgbotapi.Location, stops []transit.Stop) tgbotapi.InlineKeyboardMarkup {
+	refresh := fmt.Sprintf("//publicbus_refresh %f %f", loc.Longitude, loc.Latitude)
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		makeRefreshRow(refresh),
+	}
+	rows = append(rows, makeStarButtons("public", stops)...)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
 
-		resp, _ := client.Do(req)
-		responseData, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Print(err)
-		}
+// makePublicBusKeyboard_Stop returns the keyboard for the timings at a stop which was searched for
+func makePublicBusKeyboard_Stop(stop transit.Stop) tgbotapi.InlineKeyboardMarkup {
+	refresh := "//publicbus_stop " + stop.Code
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		makeRefreshRow(refresh),
+	}
+	rows = append(rows, makeStarButtons("public", []transit.Stop{stop})...)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
 
-		bt := busTimes{}
-		if err := json.Unmarshal(responseData, &bt); err != nil {
-			log.Print(err)
-		}
-		for j := 0; j < len(bt.Services); j++ {
-			arrivalTime := bt.Services[j].Next.EstimatedArrival
-
-			layout := "2006-01-02T15:04:05-07:00"
-			t, _ := time.Parse(layout, arrivalTime)
-			duration := int(t.Sub(time.Now()).Minutes())
-			s := "🚍Bus " + bt.Services[j].ServiceNum + " : " + strconv.Itoa(duration+1) + " minutes"
-			lines = append(lines, s)
-		}
-		lines = append(lines, "")
+// makePublicBusPicker returns a keyboard to choose one of the stops found by a search
+func makePublicBusPicker(stops []transit.Stop) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(stops))
+	for _, stop := range stops {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(stop.Name+" ("+stop.Code+")", "//publicbus_stop "+stop.Code),
+		))
 	}
-	lines = append(lines, "Last updated: "+time.Now().Format(time.RFC822))
-	return strings.Join(lines, "\n")
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
-//// REFRESH BUTTONS
+//// STOP SEARCH
 
-func makeNUSBusKeyboard_Buttons(code string) tgbotapi.InlineKeyboardMarkup {
-	return tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Refresh", "//nusbus_refresh "+code),
-		),
-	)
-}
+// maxStopResults is the most stops offered when a search matches several stops
+const maxStopResults = 8
 
-func makeNUSBusKeyboard_Location(loc tgbotapi.Location) tgbotapi.InlineKeyboardMarkup {
-	return tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Refresh", fmt.Sprintf("//nusbus_loc_refresh %f %f", loc.Longitude, loc.Latitude)),
-		),
-	)
-}
+var stopCodePattern = regexp.MustCompile(`^\d{5}$`)
+
+// searchStops returns the stops matching query, best match first. A 5 digit query is taken to be
+// a stop code, and anything else is matched against the names of the stops.
+func searchStops(stops []transit.Stop, query string) []transit.Stop {
+	query = strings.TrimSpace(query)
+	if stopCodePattern.MatchString(query) {
+		for _, stop := range stops {
+			if stop.Code == query {
+				return []transit.Stop{stop}
+			}
+		}
+		return nil
+	}
 
-func makePublicBusKeyboard(loc tgbotapi.Location) tgbotapi.InlineKeyboardMarkup {
-	return tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Refresh", fmt.Sprintf("//publicbus_refresh %f %f", loc.Longitude, loc.Latitude)),
-		),
-	)
+	names := make([]string, len(stops))
+	for i, stop := range stops {
+		names[i] = stop.Name
+	}
+	matches := utils.FuzzyRank(query, names)
+	if len(matches) > maxStopResults {
+		matches = matches[:maxStopResults]
+	}
+	results := make([]transit.Stop, len(matches))
+	for i, match := range matches {
+		results[i] = stops[match]
+	}
+	return results
 }
 
 //// CINNABOT FUNCTIONS
 
 //NUSBus retrieves the next timing for NUS Shuttle buses
 func (cb *Cinnabot) NUSBus(msg *message) {
+	cb.useSavedPlace(msg)
 	//If no args in nusbus and arg not relevant to bus
 	if len(msg.Args) == 0 || !cb.CheckArgCmdPair("/nusbus", msg.Args) {
 		opt1 := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("UTown"), tgbotapi.NewKeyboardButton("Science"))
@@ -295,7 +323,7 @@ func (cb *Cinnabot) NUSBus(msg *message) {
 		opt6B.RequestLocation = true
 		opt6 := tgbotapi.NewKeyboardButtonRow(opt6B)
 
-		options := tgbotapi.NewReplyKeyboard(opt6, opt1, opt2, opt3, opt4, opt5)
+		options := cb.locationKeyboard(msg.From.ID, opt6, opt1, opt2, opt3, opt4, opt5)
 		options.ResizeKeyboard = true
 		options.OneTimeKeyboard = true
 		options.Selective = true
@@ -311,10 +339,7 @@ func (cb *Cinnabot) NUSBus(msg *message) {
 
 	if msg.Location != nil {
 		loc = msg.Location
-		//Returns a heap of busstop data (sorted)
-		BSH := makeNUSHeap(*loc)
-		responseString := nusBusResponse_Location(&BSH)
-		responseKeyboard := makeNUSBusKeyboard_Location(*loc)
+		responseString, responseKeyboard := cb.nusBusNearby(*loc)
 		response := NewMessageWithButton(responseString, responseKeyboard, msg.Chat.ID)
 		cb.SendMessage(response)
 		return
@@ -328,7 +353,7 @@ func (cb *Cinnabot) NUSBus(msg *message) {
 	}
 
 	// Build response components
-	responseString, ok := nusBusResponse_Buttons(code)
+	responseString, ok := nusBusResponse_Buttons(cb.nusBus, code, time.Now())
 	if !ok {
 		cb.SendTextMessage(int(msg.Chat.ID), "Invalid location!")
 		return
@@ -340,46 +365,107 @@ func (cb *Cinnabot) NUSBus(msg *message) {
 
 //BusTimings checks the public bus timings based on given location
 func (cb *Cinnabot) PublicBus(msg *message) {
+	cb.useSavedPlace(msg)
 	if len(msg.Args) == 0 || !cb.CheckArgCmdPair("/publicbus", msg.Args) {
 		opt1 := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("Cinnamon"))
 		opt2B := tgbotapi.NewKeyboardButton("Here")
 		opt2B.RequestLocation = true
 		opt2 := tgbotapi.NewKeyboardButtonRow(opt2B)
 
-		options := tgbotapi.NewReplyKeyboard(opt1, opt2)
+		options := cb.locationKeyboard(msg.From.ID, opt1, opt2)
 
-		replyMsg := tgbotapi.NewMessage(msg.Chat.ID, "🤖: Where are you?\n\n")
+		replyMsg := tgbotapi.NewMessage(msg.Chat.ID, "🤖: Where are you?\nYou can also send me a bus stop code or name.\n\n")
 		replyMsg.ReplyMarkup = options
 		cb.SendMessage(replyMsg)
 		return
 	}
 	//Asynchronous
 
+	query := strings.Join(msg.Args, " ")
+	if msg.Location == nil && strings.ToLower(query) != "cinnamon" {
+		cb.publicBusSearch(msg.Chat.ID, query)
+		return
+	}
+
 	//Default loc: Cinnamon
 	loc := &tgbotapi.Location{Latitude: 1.306671, Longitude: 103.773556}
 
 	if msg.Location != nil {
 		loc = msg.Location
 	}
-	//Returns a heap of busstop data (sorted)
-	BSH := makeHeap(*loc)
-	responseString := publicBusResponse(&BSH)
-	responseKeyboard := makePublicBusKeyboard(*loc)
+	responseString, responseKeyboard := cb.publicBusNearby(*loc)
 	response := NewMessageWithButton(responseString, responseKeyboard, msg.Chat.ID)
 	cb.SendMessage(response)
 }
 
+// Most stops shown for a location, to keep messages short
+const (
+	maxNearbyNUSStops    = 3
+	maxNearbyPublicStops = 4
+)
+
+func noNearbyStopsStr(radius float64) string {
+	return fmt.Sprintf("🤖: There are no bus stops within %.0f m of you.", radius)
+}
+
+// nusBusNearby returns the NUS bus timings at the stops near loc, and the keyboard for them
+func (cb *Cinnabot) nusBusNearby(loc tgbotapi.Location) (string, tgbotapi.InlineKeyboardMarkup) {
+	stops := nearestStops(cb.nusBus, loc, maxNearbyNUSStops, cb.keys.NearbyStopRadius)
+	if len(stops) == 0 {
+		return noNearbyStopsStr(cb.keys.NearbyStopRadius), makeNUSBusKeyboard_Location(loc, stops)
+	}
+	return nusBusResponse_Location(cb.nusBus, loc, stops, time.Now()), makeNUSBusKeyboard_Location(loc, stops)
+}
+
+// publicBusNearby returns the public bus timings at the stops near loc, and the keyboard for them
+func (cb *Cinnabot) publicBusNearby(loc tgbotapi.Location) (string, tgbotapi.InlineKeyboardMarkup) {
+	stops := nearestStops(cb.publicBus, loc, maxNearbyPublicStops, cb.keys.NearbyStopRadius)
+	if len(stops) == 0 {
+		return noNearbyStopsStr(cb.keys.NearbyStopRadius), makePublicBusKeyboard(loc, stops)
+	}
+	return publicBusResponse(cb.publicBus, &loc, stops, time.Now()), makePublicBusKeyboard(loc, stops)
+}
+
 //NUSBusHome updates the inline keyboard to a bus stop selector keyboard
 /* func (cb *Cinnabot) NUSBusHome(qry *Callback) {
 	return // To be implemented if bus times is migrated to inline keyboard
 } */
 
+// publicBusSearch sends the timings at the stop matching query, or a picker if several stops match
+func (cb *Cinnabot) publicBusSearch(chatID int64, query string) {
+	stops, err := cb.publicBus.Stops()
+	if err != nil {
+		log.Print(err)
+		cb.SendTextMessage(int(chatID), "Something went wrong while searching for bus stops")
+		return
+	}
+
+	matches := searchStops(stops, query)
+	switch len(matches) {
+	case 0:
+		cb.SendTextMessage(int(chatID), "🤖: I couldn't find any bus stop matching \""+escapeMarkdown(query)+"\". Try another name or a 5 digit bus stop code.")
+	case 1:
+		responseString := publicBusResponse(cb.publicBus, nil, matches, time.Now())
+		cb.SendMessage(NewMessageWithButton(responseString, makePublicBusKeyboard_Stop(matches[0]), chatID))
+	default:
+		cb.SendMessage(NewMessageWithButton("🤖: Which bus stop did you mean?", makePublicBusPicker(matches), chatID))
+	}
+}
+
+//PublicBusStop handles the choice of stop from a /publicbus search, and the refresh button of its timings
+func (cb *Cinnabot) PublicBusStop(qry *Callback) {
+	code := qry.GetArgString()
+	stop := transit.Stop{Code: code, Name: stopName(cb.publicBus, code)}
+	responseString := publicBusResponse(cb.publicBus, nil, []transit.Stop{stop}, time.Now())
+	cb.SendMessage(EditedMessageWithButton(responseString, makePublicBusKeyboard_Stop(stop), qry.ChatID, qry.MsgID))
+}
+
 //// REFERSH BUTTON HANDLERS
 
 //NUSBusRefresh_Buttons handles the refresh button for messages from /nusbus -> location button
 func (cb *Cinnabot) NUSBusRefresh_Buttons(qry *Callback) {
 	code := qry.GetArgString()
-	responseString, ok := nusBusResponse_Buttons(code)
+	responseString, ok := nusBusResponse_Buttons(cb.nusBus, code, time.Now())
 	if !ok {
 		cb.SendTextMessage(int(qry.ChatID), "Something went wrong while refreshing bus timings")
 		return
@@ -395,9 +481,7 @@ func (cb *Cinnabot) NUSBusRefresh_Location(qry *Callback) {
 	lat, _ := strconv.ParseFloat(qry.Args[1], 64)
 
 	loc := tgbotapi.Location{Longitude: long, Latitude: lat}
-	heap := makeNUSHeap(loc)
-	responseString := nusBusResponse_Location(&heap)
-	responseKeyboard := makeNUSBusKeyboard_Location(loc)
+	responseString, responseKeyboard := cb.nusBusNearby(loc)
 	response := EditedMessageWithButton(responseString, responseKeyboard, qry.ChatID, qry.MsgID)
 	cb.SendMessage(response)
 }
@@ -408,9 +492,7 @@ func (cb *Cinnabot) PublicBusRefresh(qry *Callback) {
 	lat, _ := strconv.ParseFloat(qry.Args[1], 64)
 
 	loc := tgbotapi.Location{Longitude: long, Latitude: lat}
-	BSH := makeHeap(loc)
-	responseString := publicBusResponse(&BSH)
-	responseKeyboard := makePublicBusKeyboard(loc)
+	responseString, responseKeyboard := cb.publicBusNearby(loc)
 	response := EditedMessageWithButton(responseString, responseKeyboard, qry.ChatID, qry.MsgID)
 	cb.SendMessage(response)
 }
@@ -0,0 +1,232 @@
+package cinnabot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/usdevs/cinnabot/model"
+	"github.com/usdevs/cinnabot/transit"
+	"github.com/usdevs/cinnabot/utils"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// recordedTime is the time at which the bus timings in testdata were recorded
+var recordedTime = time.Date(2020, 10, 15, 10, 0, 0, 0, utils.SgLocation())
+
+func fakeNUSClient() *transit.Client {
+	return transit.NewClient(transit.NewFakeNUS("main/nusstops.json", "testdata/nus", recordedTime), time.Minute, time.Second)
+}
+
+func fakePublicBusClient() *transit.Client {
+	return transit.NewClient(transit.NewFakeLTA("main/publicstops.json", "testdata/lta", recordedTime), time.Minute, time.Second)
+}
+
+func TestFormatNUSBusTimings(t *testing.T) {
+	now := time.Now()
+	result := transit.StopArrivals{
+		Code: "COM2",
+		Arrivals: []transit.Arrival{
+			{Service: "A1", Buses: []transit.Bus{{At: now}, {At: now.Add(7 * time.Minute)}}},
+			{Service: "D1", Buses: []transit.Bus{{At: now.Add(time.Minute)}, {At: now.Add(9 * time.Minute)}}},
+			{Service: "D2", Buses: []transit.Bus{{At: now.Add(4 * time.Minute)}}},
+			{Service: "BTC1"},
+		},
+	}
+	expected := "*COM2*\n" +
+		"🚍A1 : Arr, 7 mins\n" +
+		"🚍D1 : 1 min, 9 mins\n" +
+		"🚍D2 : 4 mins, - mins\n" +
+		"🛑BTC1 : - mins\n"
+	if got := formatNUSBusTimings("COM2", "", result, now); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	unavailable := transit.StopArrivals{Code: "COM2", Err: errors.New("timeout")}
+	expected = "*COM2*\nTimings unavailable\n"
+	if got := formatNUSBusTimings("COM2", "", unavailable, now); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestNUSBusResponse(t *testing.T) {
+	client := fakeNUSClient()
+
+	got, ok := nusBusResponse_Buttons(client, "Science", recordedTime)
+	if !ok {
+		t.Fatal("science should be a valid location")
+	}
+	expected := "🤖: Here are the bus timings\n" +
+		"*S17*\n🚍A1 : 5 mins, 16 mins\n🛑D2 : - mins\n\n" +
+		"*LT27*\n🚍A2 : 2 mins, 13 mins\n🚍D2 : 7 mins, 19 mins\n\n" +
+		"Last updated: 15 Oct 20 10:00 +08"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if _, ok := nusBusResponse_Buttons(client, "nowhere", recordedTime); ok {
+		t.Error("nowhere should not be a valid location")
+	}
+
+	// Cinnamon: UTown is recorded, but the next 2 closest stops are not
+	cinnamon := tgbotapi.Location{Latitude: 1.306671, Longitude: 103.773556}
+	got = nusBusResponse_Location(client, cinnamon, nearestStops(client, cinnamon, 3, 800), recordedTime)
+	expected = "🤖: Here are the bus timings\n" +
+		"*University Town* · 360 m · ~5 min walk\n🛑A1 : - mins\n🛑A2 : - mins\n🚍C : 3 mins, 14 mins\n🚍D1 : Arr, 9 mins\n🚍D2 : 1 min, 12 mins\n🚍BTC1 : 12 mins, - mins\n\n" +
+		"*Museum* · 620 m · ~8 min walk\nTimings unavailable\n\n" +
+		"*Raffles Hall* · 630 m · ~8 min walk\nTimings unavailable\n\n" +
+		"Last updated: 15 Oct 20 10:00 +08"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestPublicBusResponse(t *testing.T) {
+	client := fakePublicBusClient()
+	cinnamon := tgbotapi.Location{Latitude: 1.306671, Longitude: 103.773556}
+
+	got := publicBusResponse(client, &cinnamon, nearestStops(client, cinnamon, 4, 800), recordedTime)
+	expected := "🤖: Here are the bus timings\n" +
+		"*Aft Dover Rd* · 250 m · ~4 min walk\n" +
+		"🚍Bus 95 : 4 min 🟢 ♿, 16 min 🟡 ♿, 28 min\\* 🔴 DD\n" +
+		"🚍Bus 196 : 9 min 🟢 ♿ DD, 21 min\\* 🟢 ♿\n\n" +
+		"*New Town Sec Sch* · 260 m · ~4 min walk\n" +
+		"🚍Bus 183 : 2 min 🔴 ♿, 13 min 🟢 ♿, 25 min\\* 🟢 ♿\n\n" +
+		"*University Town* · 270 m · ~4 min walk\n" +
+		"🚍Bus 96 : 1 min 🟡 ♿ DD, 10 min 🟢 ♿\n" +
+		"🛑Bus 151 : not in operation\n\n" +
+		"*Aft Clementi Ave 1* · 350 m · ~5 min walk\nTimings unavailable\n\n" +
+		publicBusLegend + "\n" +
+		"Last updated: 15 Oct 20 10:00 +08"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestNearestStops(t *testing.T) {
+	client := fakePublicBusClient()
+	cinnamon := tgbotapi.Location{Latitude: 1.306671, Longitude: 103.773556}
+
+	got := stopCodes(nearestStops(client, cinnamon, 4, 300))
+	expected := []string{"17099", "19051", "19059"} // 17091 is 345 m away
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := nearestStops(client, cinnamon, 4, 100); len(got) != 0 {
+		t.Errorf("expected no stops within 100 m, got %v", got)
+	}
+}
+
+func TestFormatDistance(t *testing.T) {
+	tests := []struct {
+		metres   float64
+		expected string
+	}{
+		{4, "0 m · ~1 min walk"},
+		{118, "120 m · ~2 min walk"},
+		{1250, "1.2 km · ~16 min walk"},
+	}
+	for _, test := range tests {
+		if got := formatDistance(test.metres); got != test.expected {
+			t.Errorf("%.0f m: expected %q, got %q", test.metres, test.expected, got)
+		}
+	}
+}
+
+func TestMyBusResponse(t *testing.T) {
+	favourites := []model.FavouriteStop{
+		{Kind: "public", Code: "19051", Name: "New Town Sec Sch"},
+		{Kind: "nus", Code: "S17", Name: "S17"},
+	}
+	got := myBusResponse(fakeNUSClient(), fakePublicBusClient(), favourites, recordedTime)
+	expected := "🤖: Here are the bus timings at your favourite stops\n" +
+		"*New Town Sec Sch*\n🚍Bus 183 : 2 min 🔴 ♿, 13 min 🟢 ♿, 25 min\\* 🟢 ♿\n\n" +
+		"*S17*\n🚍A1 : 5 mins, 16 mins\n🛑D2 : - mins\n\n" +
+		publicBusLegend + "\n" +
+		"Last updated: 15 Oct 20 10:00 +08"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestAlertDue(t *testing.T) {
+	now := time.Now()
+	arrivals := []transit.Arrival{
+		{Service: "A1", Buses: []transit.Bus{{At: now.Add(4 * time.Minute)}}},
+		{Service: "D2"},
+	}
+	tests := []struct {
+		service string
+		minutes int
+		due     bool
+	}{
+		{"A1", 5, true},
+		{"A1", 4, true},
+		{"A1", 3, false},
+		{"D2", 15, false}, // not in operation
+		{"K", 15, false},  // does not serve the stop
+	}
+	for _, test := range tests {
+		alert := busAlert{Service: test.service, Minutes: test.minutes}
+		if _, due := alertDue(alert, arrivals, now); due != test.due {
+			t.Errorf("alert for %s within %d mins: expected due %t, got %t", test.service, test.minutes, test.due, due)
+		}
+	}
+}
+
+func TestBusAlertsCap(t *testing.T) {
+	alerts := newBusAlerts()
+	for i := 0; i < maxAlertsPerUser; i++ {
+		if err := alerts.add(busAlert{UserID: 1, Code: "S17", Service: string(rune('A' + i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := alerts.add(busAlert{UserID: 1, Code: "S17", Service: "Z"}); err != errTooManyAlerts {
+		t.Errorf("expected errTooManyAlerts, got %v", err)
+	}
+	// Replacing an existing alert does not count against the cap
+	if err := alerts.add(busAlert{UserID: 1, Code: "S17", Service: "A", Minutes: 5}); err != nil {
+		t.Errorf("expected existing alert to be replaced, got %v", err)
+	}
+	if err := alerts.add(busAlert{UserID: 2, Code: "S17", Service: "A"}); err != nil {
+		t.Errorf("expected other users to be unaffected, got %v", err)
+	}
+
+	alerts.remove(alerts.list(1)[0])
+	if got := len(alerts.list(1)); got != maxAlertsPerUser-1 {
+		t.Errorf("expected %d alerts after removing one, got %d", maxAlertsPerUser-1, got)
+	}
+}
+
+func TestRefreshRow(t *testing.T) {
+	if row := makeRefreshRow("//publicbus_refresh 103.776123 1.296456"); len(row) != 2 {
+		t.Errorf("expected refresh and alert buttons, got %d buttons", len(row))
+	}
+	if row := makeRefreshRow("//nusbus_refresh Kent Ridge MRT / Opp Kent Ridge MRT / Kent Vale"); len(row) != 1 {
+		t.Errorf("expected the alert button to be left out when its data is too long, got %d buttons", len(row))
+	}
+}
+
+func TestSearchStops(t *testing.T) {
+	stops, err := transit.ReadStops("main/publicstops.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := searchStops(stops, "17099")
+	if len(got) != 1 || got[0].Name != "Aft Dover Rd" {
+		t.Errorf("expected stop code 17099 to find Aft Dover Rd, got %v", got)
+	}
+	if got := searchStops(stops, "00000"); len(got) != 0 {
+		t.Errorf("expected unknown stop code to find nothing, got %v", got)
+	}
+
+	got = searchStops(stops, "university town")
+	if len(got) == 0 || got[0].Code != "19059" {
+		t.Errorf("expected exact name match to be ranked first, got %v", got)
+	}
+	if got := searchStops(stops, "clementi"); len(got) != maxStopResults {
+		t.Errorf("expected %d results for a common name, got %d", maxStopResults, len(got))
+	}
+}
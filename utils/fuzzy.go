@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+// Scores given by FuzzyScore, from best to worst kind of match
+const (
+	exactMatch       = 100
+	prefixMatch      = 80
+	wordPrefixMatch  = 60
+	substringMatch   = 40
+	allWordsMatch    = 30
+	subsequenceMatch = 10
+)
+
+// FuzzyScore returns how well query matches target, ignoring case and surrounding whitespace.
+// Higher scores are better matches, and 0 means that target does not match at all.
+func FuzzyScore(query, target string) int {
+	q := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	t := strings.ToLower(strings.Join(strings.Fields(target), " "))
+	if q == "" {
+		return 0
+	}
+
+	switch {
+	case q == t:
+		return exactMatch
+	case strings.HasPrefix(t, q):
+		return prefixMatch
+	case strings.Contains(t, " "+q):
+		return wordPrefixMatch
+	case strings.Contains(t, q):
+		return substringMatch
+	case allWordsPrefix(strings.Fields(q), strings.Fields(t)):
+		return allWordsMatch
+	}
+
+	// Characters of the query appear in order, eg. "ctwn" in "clementi town"
+	// Shorter targets are more likely to be what was meant
+	if isSubsequence(q, t) {
+		score := subsequenceMatch - (len(t)-len(q))/10
+		if score < 1 {
+			score = 1
+		}
+		return score
+	}
+	return 0
+}
+
+// allWordsPrefix checks if every query word is the prefix of some target word
+func allWordsPrefix(queryWords, targetWords []string) bool {
+	if len(queryWords) < 2 {
+		return false
+	}
+	for _, qw := range queryWords {
+		found := false
+		for _, tw := range targetWords {
+			if strings.HasPrefix(tw, qw) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// isSubsequence checks if the runes of q appear in t in order
+func isSubsequence(q, t string) bool {
+	qr := []rune(strings.Replace(q, " ", "", -1))
+	i := 0
+	for _, r := range t {
+		if i < len(qr) && r == qr[i] {
+			i++
+		}
+	}
+	return i == len(qr)
+}
+
+// FuzzyRank returns the indices of the targets which match query, best match first.
+// Targets which match equally well keep their original order.
+func FuzzyRank(query string, targets []string) []int {
+	scores := make([]int, len(targets))
+	matches := make([]int, 0)
+	for i, target := range targets {
+		scores[i] = FuzzyScore(query, target)
+		if scores[i] > 0 {
+			matches = append(matches, i)
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return scores[matches[a]] > scores[matches[b]]
+	})
+	return matches
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, target string
+		expected      int
+	}{
+		{"University Town", "university town", exactMatch},
+		{"univ", "University Town", prefixMatch},
+		{"town", "University Town", wordPrefixMatch},
+		{"own", "University Town", substringMatch},
+		{"clem ave", "Aft Clementi Ave 1", allWordsMatch},
+		{"xyz", "University Town", 0},
+		{"", "University Town", 0},
+	}
+	for _, test := range tests {
+		if got := FuzzyScore(test.query, test.target); got != test.expected {
+			t.Errorf("FuzzyScore(%q, %q): expected %d, got %d", test.query, test.target, test.expected, got)
+		}
+	}
+
+	if got := FuzzyScore("utwn", "University Town"); got <= 0 || got >= allWordsMatch {
+		t.Errorf("FuzzyScore(%q, %q): expected a subsequence match, got %d", "utwn", "University Town", got)
+	}
+}
+
+func TestFuzzyRank(t *testing.T) {
+	targets := []string{"Aft Clementi Ave 1", "Opp Clementi Stn", "Clementi Stn", "University Town", "Clementi Int"}
+	got := FuzzyRank("clementi", targets)
+	expected := []int{2, 4, 0, 1}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
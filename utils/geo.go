@@ -0,0 +1,16 @@
+package utils
+
+import "math"
+
+// earthRadius is the mean radius of the earth in metres
+const earthRadius = 6371000
+
+// Haversine returns the great-circle distance in metres between two points given in degrees.
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		expected               float64 // metres
+	}{
+		{"same point", 1.306671, 103.773556, 1.306671, 103.773556, 0},
+		{"Cinnamon to a point due south", 1.306671, 103.773556, 1.30358, 103.77353, 344},
+		{"one degree of longitude at the equator", 0, 103, 0, 104, 111195},
+	}
+	for _, test := range tests {
+		got := Haversine(test.lat1, test.lng1, test.lat2, test.lng2)
+		if math.Abs(got-test.expected) > 1 {
+			t.Errorf("%s: expected %.0f m, got %.0f m", test.name, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package cinnabot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/usdevs/cinnabot/transit"
+	"github.com/usdevs/cinnabot/utils"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+const (
+	maxVenueResults = 6
+	venueMapRadius  = 500 // metres, maps further than this from a venue are not sent with it
+)
+
+// venue is a room on campus which can be found with /where
+type venue struct {
+	Code      string
+	RoomName  string
+	Floor     string // eg. "2", or "B1" for a basement
+	Latitude  float64
+	Longitude float64
+}
+
+// building returns the building a venue is in, eg. "COM1" for COM1-0210
+func (v venue) building() string {
+	if i := strings.Index(v.Code, "-"); i > 0 {
+		return v.Code[:i]
+	}
+	return ""
+}
+
+// location returns where a venue is
+func (v venue) location() tgbotapi.Location {
+	return tgbotapi.Location{Latitude: v.Latitude, Longitude: v.Longitude}
+}
+
+// venueFloor is the floor of a venue in the NUSMods data, which is usually a number but may be
+// a string like "B1"
+type venueFloor string
+
+func (f *venueFloor) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*f = venueFloor(number.String())
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("floor must be a number or a string: %s", data)
+	}
+	*f = venueFloor(s)
+	return nil
+}
+
+// readVenues reads venues from a file in the format of NUSMods' venues.json, sorted by code
+func readVenues(path string) ([]venue, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]struct {
+		RoomName string     `json:"roomName"`
+		Floor    venueFloor `json:"floor"`
+		Location *struct {
+			X float64 `json:"x"` // longitude
+			Y float64 `json:"y"` // latitude
+		} `json:"location"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	venues := make([]venue, 0, len(raw))
+	for code, v := range raw {
+		// Venues without a location can't be shown on a map
+		if v.Location == nil {
+			continue
+		}
+		venues = append(venues, venue{
+			Code:      code,
+			RoomName:  v.RoomName,
+			Floor:     string(v.Floor),
+			Latitude:  v.Location.Y,
+			Longitude: v.Location.X,
+		})
+	}
+	sort.Slice(venues, func(i, j int) bool { return venues[i].Code < venues[j].Code })
+	return venues, nil
+}
+
+// normaliseVenueCode turns a query into the form of a venue code, eg. "com1 0210" into "COM1-0210"
+func normaliseVenueCode(query string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(query), "-"))
+}
+
+// searchVenues returns up to maxVenueResults venues matching query, best match first. A venue
+// whose code is the query is the only result.
+func searchVenues(venues []venue, query string) []venue {
+	code := normaliseVenueCode(query)
+	for _, v := range venues {
+		if v.Code == code {
+			return []venue{v}
+		}
+	}
+
+	targets := make([]string, len(venues))
+	for i, v := range venues {
+		targets[i] = v.Code + " " + v.RoomName
+	}
+	results := make([]venue, 0)
+	for _, i := range utils.FuzzyRank(query, targets) {
+		if len(results) == maxVenueResults {
+			break
+		}
+		results = append(results, venues[i])
+	}
+	return results
+}
+
+// formatFloor formats the floor of a venue, eg. "level 2" or "basement 1"
+func formatFloor(floor string) string {
+	if n, err := strconv.Atoi(floor); err == nil && n < 0 {
+		return "basement " + strconv.Itoa(-n)
+	}
+	if strings.HasPrefix(floor, "B") {
+		return "basement " + floor[1:]
+	}
+	return "level " + floor
+}
+
+// venueText describes where a venue is. The nearest shuttle stop and map are left out if there
+// are none.
+func venueText(v venue, stops []transit.Stop, m *campusMap) string {
+	text := "🤖: *" + v.Code + "*"
+	if v.RoomName != "" && v.RoomName != v.Code {
+		text += " · " + v.RoomName
+	}
+	text += "\n"
+	if b := v.building(); b != "" {
+		text += "🏢 " + b + ", " + formatFloor(v.Floor) + "\n"
+	} else if v.Floor != "" {
+		text += "🏢 " + strings.Title(formatFloor(v.Floor)) + "\n"
+	}
+	if len(stops) > 0 {
+		text += "🚏 Nearest shuttle stop: " + stopHeading(stops[0].Name, formatDistance(distanceBetween2(v.location(), stops[0]))) + "\n"
+	}
+	if m != nil {
+		text += "🗺 Map: " + m.Name + "\n"
+	}
+	return strings.TrimSuffix(text, "\n")
+}
+
+// venuePicker returns buttons for choosing between venues
+func venuePicker(venues []venue) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, v := range venues {
+		label := v.Code
+		if v.RoomName != "" && v.RoomName != v.Code {
+			label += " · " + v.RoomName
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(label, "//where "+v.Code)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// sendVenue sends where a venue is, a pin at its location and the map of that part of campus
+func (cb *Cinnabot) sendVenue(chatID int64, v venue) {
+	stops := nearestStops(cb.nusBus, v.location(), 1, math.Inf(1))
+	var m *campusMap
+	if cb.maps != nil {
+		if nearest, ok := cb.maps.nearest(v.Latitude, v.Longitude, venueMapRadius); ok {
+			m = &nearest
+		}
+	}
+
+	cb.SendTextMessage(int(chatID), venueText(v, stops, m))
+	address := v.RoomName
+	if b := v.building(); b != "" {
+		address = b + ", " + formatFloor(v.Floor)
+	}
+	if _, err := cb.bot.Send(tgbotapi.NewVenue(chatID, v.Code, address, v.Latitude, v.Longitude)); err != nil {
+		cb.log.Printf("cannot send location of %s: %s", v.Code, err)
+	}
+	if m != nil {
+		cb.sendMap(chatID, *m)
+	}
+}
+
+// Where finds a venue by its code or name, eg. /where COM1-0210 or /where lt27
+func (cb *Cinnabot) Where(msg *message) {
+	if len(cb.venues) == 0 {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Sorry, venues are unavailable right now.")
+		return
+	}
+	query := strings.TrimSpace(msg.GetArgString())
+	if query == "" {
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: Which venue are you looking for? Send its code or name, eg. COM1-0210 or LT27")
+		return
+	}
+
+	venues := searchVenues(cb.venues, query)
+	switch len(venues) {
+	case 0:
+		cb.SendTextMessage(int(msg.Chat.ID), "🤖: I couldn't find that venue. Try a venue code like COM1-0210 or LT27.")
+	case 1:
+		cb.sendVenue(msg.Chat.ID, venues[0])
+	default:
+		cb.SendMessage(NewMessageWithButton("🤖: Which one did you mean?", venuePicker(venues), msg.Chat.ID))
+	}
+}
+
+// WhereVenue sends the venue picked from the buttons under a /where search
+func (cb *Cinnabot) WhereVenue(qry *Callback) {
+	if len(qry.Args) < 1 {
+		return
+	}
+	for _, v := range cb.venues {
+		if v.Code == qry.Args[0] {
+			cb.sendVenue(qry.ChatID, v)
+			return
+		}
+	}
+}
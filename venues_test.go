@@ -0,0 +1,123 @@
+package cinnabot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/usdevs/cinnabot/transit"
+)
+
+func TestVenuesData(t *testing.T) {
+	venues, err := readVenues("main/venues.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(venues) == 0 {
+		t.Fatal("no venues")
+	}
+	for _, v := range venues {
+		if v.Floor == "" {
+			t.Errorf("venue %s has no floor", v.Code)
+		}
+		// Roughly the bounds of the Kent Ridge campus
+		if v.Latitude < 1.28 || v.Latitude > 1.31 || v.Longitude < 103.76 || v.Longitude > 103.79 {
+			t.Errorf("venue %s is at %f, %f, which is not in NUS", v.Code, v.Latitude, v.Longitude)
+		}
+	}
+}
+
+func TestVenueFloor(t *testing.T) {
+	tests := []struct {
+		json, floor, formatted string
+	}{
+		{`2`, "2", "level 2"},
+		{`-1`, "-1", "basement 1"},
+		{`"B1"`, "B1", "basement 1"},
+		{`"3"`, "3", "level 3"},
+	}
+	for _, test := range tests {
+		var f venueFloor
+		if err := json.Unmarshal([]byte(test.json), &f); err != nil {
+			t.Errorf("%s: %s", test.json, err)
+			continue
+		}
+		if string(f) != test.floor {
+			t.Errorf("%s: got floor %q, want %q", test.json, f, test.floor)
+		}
+		if got := formatFloor(string(f)); got != test.formatted {
+			t.Errorf("formatFloor(%q) = %q, want %q", f, got, test.formatted)
+		}
+	}
+	var f venueFloor
+	if err := json.Unmarshal([]byte(`{}`), &f); err == nil {
+		t.Error("expected an error for an object")
+	}
+}
+
+func TestSearchVenues(t *testing.T) {
+	venues := []venue{
+		{Code: "COM1-0208", RoomName: "SR5"},
+		{Code: "COM1-0210", RoomName: "SR10"},
+		{Code: "LT27", RoomName: "Lecture Theatre 27"},
+	}
+	codes := func(vs []venue) []string {
+		var codes []string
+		for _, v := range vs {
+			codes = append(codes, v.Code)
+		}
+		return codes
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"com1-0210", []string{"COM1-0210"}},
+		{"com1 0210", []string{"COM1-0210"}},
+		{"lt27", []string{"LT27"}},
+		{"com1", []string{"COM1-0208", "COM1-0210"}},
+		{"lecture theatre", []string{"LT27"}},
+		{"sr10", []string{"COM1-0210"}},
+		{"zzz", nil},
+	}
+	for _, test := range tests {
+		got := codes(searchVenues(venues, test.query))
+		if len(got) != len(test.want) {
+			t.Errorf("searchVenues(%q) = %v, want %v", test.query, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("searchVenues(%q) = %v, want %v", test.query, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestVenueText(t *testing.T) {
+	v := venue{Code: "COM1-0210", RoomName: "SR10", Floor: "2", Latitude: 1.29512, Longitude: 103.77351}
+	stops := []transit.Stop{{Code: "COM2", Name: "COM 2", Latitude: 1.29431, Longitude: 103.7737}}
+	m := &campusMap{Key: "comp", Name: "Comp"}
+
+	want := "🤖: *COM1-0210* · SR10\n🏢 COM1, level 2\n🚏 Nearest shuttle stop: *COM 2* · 90 m · ~2 min walk\n🗺 Map: Comp"
+	if got := venueText(v, stops, m); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	want = "🤖: *COM1-0210* · SR10\n🏢 COM1, level 2"
+	if got := venueText(v, nil, nil); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMapRegistryNearest(t *testing.T) {
+	maps := &mapRegistry{maps: []campusMap{
+		{Key: "comp", Latitude: 1.295, Longitude: 103.7737},
+		{Key: "science", Latitude: 1.2966, Longitude: 103.7803},
+	}}
+	if m, ok := maps.nearest(1.29512, 103.77351, 500); !ok || m.Key != "comp" {
+		t.Errorf("got %v %v, want comp", m.Key, ok)
+	}
+	if _, ok := maps.nearest(1.3187, 103.8176, 500); ok {
+		t.Error("expected no map within 500 m of Bukit Timah")
+	}
+}
@@ -0,0 +1,158 @@
+package cinnabot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/usdevs/cinnabot/weather"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// weatherRequestTime is the time allowed for fetching all the products shown in one reply
+const weatherRequestTime = 15 * time.Second
+
+// cinnamonLoc is used for forecasts when no location is given
+var cinnamonLoc = tgbotapi.Location{Latitude: 1.306671, Longitude: 103.773556}
+
+// Weather replies with a weather forecast. /weather now, today and 4day choose the forecast,
+// otherwise the user is asked for their location for the 2h forecast there.
+func (cb *Cinnabot) Weather(msg *message) {
+	if len(msg.Args) > 0 {
+		msg.Args[0] = strings.ToLower(msg.Args[0])
+	}
+	cb.useSavedPlace(msg)
+	//Check if weather was sent with location, if not reply with markup
+	if len(msg.Args) == 0 || !cb.CheckArgCmdPair("/weather", msg.Args) {
+		opt1 := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("Cinnamon"))
+		opt2B := tgbotapi.NewKeyboardButton("Here")
+		opt2B.RequestLocation = true
+		opt2 := tgbotapi.NewKeyboardButtonRow(opt2B)
+		opt3 := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("Today"), tgbotapi.NewKeyboardButton("4day"))
+
+		options := cb.locationKeyboard(msg.From.ID, opt1, opt2, opt3)
+
+		replyMsg := tgbotapi.NewMessage(int64(msg.Message.From.ID), "🤖: Where are you?\n\n")
+		replyMsg.ReplyMarkup = options
+		cb.SendMessage(replyMsg)
+		return
+	}
+
+	//Default loc: Cinnamon
+	loc := cinnamonLoc
+	if msg.Location != nil {
+		loc = *msg.Location
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), weatherRequestTime)
+	defer cancel()
+
+	var text string
+	var err error
+	switch msg.Args[0] {
+	case "today":
+		var day weather.Day
+		if day, err = cb.weather.Day(ctx); err == nil {
+			text = formatWeatherToday(day, weather.Region(loc.Latitude, loc.Longitude))
+		}
+	case "4day":
+		var outlook []weather.Outlook
+		if outlook, err = cb.weather.FourDay(ctx); err == nil {
+			text = formatWeatherOutlook(outlook)
+		}
+	default:
+		text, err = cb.weatherNow(ctx, loc)
+	}
+	if err != nil {
+		cb.log.Printf("cannot get weather: %s", err)
+		text = weatherErrorStr(err)
+	}
+	cb.SendTextMessage(int(msg.Chat.ID), text)
+}
+
+// weatherNow fetches the 2h forecast and the latest readings near loc. The readings are left out
+// if they cannot be fetched, as the forecast is still useful without them.
+func (cb *Cinnabot) weatherNow(ctx context.Context, loc tgbotapi.Location) (string, error) {
+	forecast, err := cb.weather.TwoHour(ctx)
+	if err != nil {
+		return "", err
+	}
+	temperature, err := cb.weather.Temperature(ctx)
+	if err != nil {
+		cb.log.Printf("cannot get temperature: %s", err)
+	}
+	rainfall, err := cb.weather.Rainfall(ctx)
+	if err != nil {
+		cb.log.Printf("cannot get rainfall: %s", err)
+	}
+	return formatWeatherNow(forecast, temperature, rainfall, loc), nil
+}
+
+// describeForecast turns a forecast like "Partly Cloudy (Day)" into "partly cloudy"
+func describeForecast(forecast string) string {
+	if i := strings.Index(forecast, " ("); i >= 0 {
+		forecast = forecast[:i]
+	}
+	return strings.ToLower(forecast)
+}
+
+// formatWeatherNow formats the 2h forecast for the area nearest to loc, and the temperature and
+// rainfall at the stations nearest to it
+func formatWeatherNow(forecast weather.TwoHour, temperature, rainfall weather.Readings, loc tgbotapi.Location) string {
+	area, f, ok := forecast.Nearest(loc.Latitude, loc.Longitude)
+	if !ok {
+		return "🤖: There's no 2h forecast right now, try again later!"
+	}
+	text := fmt.Sprintf("🤖: The 2h forecast is %s for %s, until %s\n", describeForecast(f), area.Name, forecast.ValidTo.Format("3pm"))
+
+	if station, value, ok := temperature.Nearest(loc.Latitude, loc.Longitude); ok {
+		text += fmt.Sprintf("\n🌡 %.1f°C at %s", value, station.Name)
+	}
+	if station, value, ok := rainfall.Nearest(loc.Latitude, loc.Longitude); ok {
+		if value > 0 {
+			text += fmt.Sprintf("\n☔ %.1f mm of rain in the last 5 min at %s", value, station.Name)
+		} else {
+			text += fmt.Sprintf("\n🌤 No rain in the last 5 min at %s", station.Name)
+		}
+	}
+	return strings.TrimSuffix(text, "\n")
+}
+
+// formatRange formats a range of values, eg. "25–33"
+func formatRange(r weather.Range) string {
+	if r.Low == r.High {
+		return fmt.Sprintf("%.0f", r.Low)
+	}
+	return fmt.Sprintf("%.0f–%.0f", r.Low, r.High)
+}
+
+// formatWeatherToday formats the 24h forecast, with the forecast for each part of the day in region
+func formatWeatherToday(day weather.Day, region string) string {
+	text := fmt.Sprintf("🤖: *Next 24h*: %s\n%s°C, humidity %s%%\n", day.Forecast, formatRange(day.Temperature), formatRange(day.Humidity))
+	if len(day.Periods) > 0 {
+		text += "\n*" + strings.Title(region) + "*\n"
+		for _, p := range day.Periods {
+			text += fmt.Sprintf("%s–%s: %s\n", p.Start.Format("3pm"), p.End.Format("3pm"), p.Regions[region])
+		}
+	}
+	return strings.TrimSuffix(text, "\n")
+}
+
+// formatWeatherOutlook formats the 4-day outlook
+func formatWeatherOutlook(outlook []weather.Outlook) string {
+	text := "🤖: *4-day outlook*\n"
+	for _, o := range outlook {
+		text += fmt.Sprintf("\n*%s*: %s, %s°C", o.Date.Format("Mon 2 Jan"), o.Forecast, formatRange(o.Temperature))
+	}
+	return text
+}
+
+// weatherErrorStr explains to the user why the weather could not be shown
+func weatherErrorStr(err error) string {
+	if errors.Is(err, weather.ErrBadResponse) {
+		return "🤖: I couldn't understand the weather data right now, please try again later."
+	}
+	return "🤖: The weather service isn't responding right now, please try again later."
+}
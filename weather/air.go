@@ -0,0 +1,130 @@
+package weather
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// National is the key of the readings for all of Singapore in RegionReadings.
+const National = "national"
+
+// RegionReadings are the latest readings for each region of Singapore, eg. of PSI.
+type RegionReadings struct {
+	Timestamp time.Time
+	Values    map[string]float64 // region to reading
+}
+
+// UV is the latest UV index.
+type UV struct {
+	Timestamp time.Time
+	Index     float64
+}
+
+// Band is a range of readings with the same health advisory, eg. PSI 101-200 is "Unhealthy".
+type Band struct {
+	Min    float64
+	Name   string
+	Advice string
+}
+
+// psiBands are the bands of the 24-hour PSI set by NEA
+var psiBands = []Band{
+	{0, "Good", "Normal activities."},
+	{51, "Moderate", "Normal activities."},
+	{101, "Unhealthy", "Reduce prolonged or strenuous outdoor activity."},
+	{201, "Very Unhealthy", "Avoid prolonged or strenuous outdoor activity."},
+	{301, "Hazardous", "Minimise outdoor activity."},
+}
+
+// pm25Bands are the bands of the 1-hour PM2.5 concentration set by NEA, in µg/m³
+var pm25Bands = []Band{
+	{0, "Normal", "Normal activities."},
+	{56, "Elevated", "Reduce strenuous outdoor activity if you feel unwell."},
+	{151, "High", "Reduce prolonged or strenuous outdoor activity."},
+	{251, "Very High", "Avoid outdoor activity."},
+}
+
+// uvBands are the bands of the UV index
+var uvBands = []Band{
+	{0, "Low", "No protection needed."},
+	{3, "Moderate", "Seek shade around midday."},
+	{6, "High", "Use sunscreen and wear a hat."},
+	{8, "Very High", "Use sunscreen, wear a hat and seek shade."},
+	{11, "Extreme", "Avoid being outside around midday."},
+}
+
+// bandOf returns the highest band whose minimum is at most value
+func bandOf(bands []Band, value float64) Band {
+	band := bands[0]
+	for _, b := range bands {
+		if value >= b.Min {
+			band = b
+		}
+	}
+	return band
+}
+
+// PSIBand returns the health advisory band of a 24-hour PSI reading.
+func PSIBand(psi float64) Band {
+	return bandOf(psiBands, psi)
+}
+
+// PM25Band returns the health advisory band of a 1-hour PM2.5 reading.
+func PM25Band(pm25 float64) Band {
+	return bandOf(pm25Bands, pm25)
+}
+
+// UVBand returns the band of a UV index.
+func UVBand(index float64) Band {
+	return bandOf(uvBands, index)
+}
+
+// PSIBands returns the bands of the 24-hour PSI, from best to worst.
+func PSIBands() []Band {
+	return append([]Band(nil), psiBands...)
+}
+
+// regionReadingsJSON is the format of the PSI and PM2.5 APIs, which give several kinds of
+// readings for each region
+type regionReadingsJSON struct {
+	Items []struct {
+		Timestamp time.Time                     `json:"timestamp"`
+		Readings  map[string]map[string]float64 `json:"readings"`
+	} `json:"items"`
+}
+
+// parseRegionReadings parses the readings of one kind from a PSI or PM2.5 response,
+// eg. "psi_twenty_four_hourly"
+func parseRegionReadings(body []byte, kind string) (RegionReadings, error) {
+	var raw regionReadingsJSON
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return RegionReadings{}, err
+	}
+	if len(raw.Items) == 0 || len(raw.Items[0].Readings[kind]) == 0 {
+		return RegionReadings{}, errNoItems
+	}
+	return RegionReadings{Timestamp: raw.Items[0].Timestamp, Values: raw.Items[0].Readings[kind]}, nil
+}
+
+// uvJSON is the format of the UV index API
+type uvJSON struct {
+	Items []struct {
+		Index []struct {
+			Value     float64   `json:"value"`
+			Timestamp time.Time `json:"timestamp"`
+		} `json:"index"`
+	} `json:"items"`
+}
+
+func parseUV(body []byte) (UV, error) {
+	var raw uvJSON
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return UV{}, err
+	}
+	if len(raw.Items) == 0 || len(raw.Items[0].Index) == 0 {
+		return UV{}, errNoItems
+	}
+	// The latest reading is first
+	latest := raw.Items[0].Index[0]
+	return UV{Timestamp: latest.Timestamp, Index: latest.Value}, nil
+}
@@ -0,0 +1,64 @@
+package weather
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAirQuality(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+	c := NewClient(server.URL+"/", "", time.Minute, time.Second)
+
+	psi, err := c.PSI(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if psi.Values[West] != 52 || psi.Values[National] != 55 {
+		t.Errorf("unexpected PSI readings %v", psi.Values)
+	}
+
+	pm25, err := c.PM25(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pm25.Values[South] != 60 {
+		t.Errorf("unexpected PM2.5 readings %v", pm25.Values)
+	}
+
+	uv, err := c.UV(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uv.Index != 7 || uv.Timestamp.Hour() != 14 {
+		t.Errorf("expected the latest UV index of 7 at 2pm, got %v at %v", uv.Index, uv.Timestamp)
+	}
+}
+
+func TestBands(t *testing.T) {
+	tests := []struct {
+		band     func(float64) Band
+		value    float64
+		expected string
+	}{
+		{PSIBand, 0, "Good"},
+		{PSIBand, 50, "Good"},
+		{PSIBand, 51, "Moderate"},
+		{PSIBand, 150, "Unhealthy"},
+		{PSIBand, 250, "Very Unhealthy"},
+		{PSIBand, 400, "Hazardous"},
+		{PM25Band, 55, "Normal"},
+		{PM25Band, 56, "Elevated"},
+		{PM25Band, 300, "Very High"},
+		{UVBand, 2, "Low"},
+		{UVBand, 7, "High"},
+		{UVBand, 12, "Extreme"},
+	}
+	for _, test := range tests {
+		if got := test.band(test.value).Name; got != test.expected {
+			t.Errorf("%v: expected %s, got %s", test.value, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the endpoint of the data.gov.sg environment APIs.
+const DefaultBaseURL = "https://api.data.gov.sg/v1/environment/"
+
+// Products of the data.gov.sg environment APIs
+const (
+	TwoHourProduct     = "2-hour-weather-forecast"
+	DayProduct         = "24-hour-weather-forecast"
+	FourDayProduct     = "4-day-weather-forecast"
+	TemperatureProduct = "air-temperature"
+	RainfallProduct    = "rainfall"
+	PSIProduct         = "psi"
+	PM25Product        = "pm25"
+	UVProduct          = "uv-index"
+)
+
+// Client fetches weather products from data.gov.sg, caching each for a short time.
+type Client struct {
+	baseURL string
+	apiKey  string
+	ttl     time.Duration // how long products are cached for
+	timeout time.Duration // deadline for a single request
+	now     func() time.Time
+
+	mu     sync.Mutex
+	cached map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	body   []byte
+	expiry time.Time
+}
+
+// NewClient returns a Client for the data.gov.sg environment APIs at baseURL, which defaults
+// to DefaultBaseURL if empty. apiKey is optional.
+func NewClient(baseURL, apiKey string, ttl, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		ttl:     ttl,
+		timeout: timeout,
+		now:     time.Now,
+		cached:  make(map[string]cacheEntry),
+	}
+}
+
+// fetch returns the latest response for a product, from the cache if it has not expired
+func (c *Client) fetch(ctx context.Context, product string) ([]byte, error) {
+	c.mu.Lock()
+	entry, ok := c.cached[product]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expiry) {
+		return entry.body, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	req, err := http.NewRequest("GET", c.baseURL+product, nil)
+	if err != nil {
+		return nil, &Error{product, ErrUnavailable, err}
+	}
+	if c.apiKey != "" {
+		req.Header.Set("api-key", c.apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, &Error{product, ErrUnavailable, err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &Error{product, ErrUnavailable, fmt.Errorf("data.gov.sg returned %s", resp.Status)}
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &Error{product, ErrUnavailable, err}
+	}
+
+	c.mu.Lock()
+	c.cached[product] = cacheEntry{body: body, expiry: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return body, nil
+}
+
+// get fetches a product and parses it with parse
+func (c *Client) get(ctx context.Context, product string, parse func([]byte) error) error {
+	body, err := c.fetch(ctx, product)
+	if err != nil {
+		return err
+	}
+	if err := parse(body); err != nil {
+		// Don't keep serving a response which cannot be parsed
+		c.mu.Lock()
+		delete(c.cached, product)
+		c.mu.Unlock()
+		return &Error{product, ErrBadResponse, err}
+	}
+	return nil
+}
+
+// TwoHour returns the latest 2-hour weather forecast.
+func (c *Client) TwoHour(ctx context.Context) (TwoHour, error) {
+	var f TwoHour
+	err := c.get(ctx, TwoHourProduct, func(body []byte) (err error) {
+		f, err = parseTwoHour(body)
+		return err
+	})
+	return f, err
+}
+
+// Day returns the latest 24-hour weather forecast.
+func (c *Client) Day(ctx context.Context) (Day, error) {
+	var d Day
+	err := c.get(ctx, DayProduct, func(body []byte) (err error) {
+		d, err = parseDay(body)
+		return err
+	})
+	return d, err
+}
+
+// FourDay returns the latest 4-day weather outlook.
+func (c *Client) FourDay(ctx context.Context) ([]Outlook, error) {
+	var o []Outlook
+	err := c.get(ctx, FourDayProduct, func(body []byte) (err error) {
+		o, err = parseFourDay(body)
+		return err
+	})
+	return o, err
+}
+
+// Temperature returns the latest air temperature readings, in °C.
+func (c *Client) Temperature(ctx context.Context) (Readings, error) {
+	return c.readings(ctx, TemperatureProduct)
+}
+
+// Rainfall returns the latest rainfall readings, in mm over the last 5 minutes.
+func (c *Client) Rainfall(ctx context.Context) (Readings, error) {
+	return c.readings(ctx, RainfallProduct)
+}
+
+func (c *Client) readings(ctx context.Context, product string) (Readings, error) {
+	var r Readings
+	err := c.get(ctx, product, func(body []byte) (err error) {
+		r, err = parseReadings(body)
+		return err
+	})
+	return r, err
+}
+
+// PSI returns the latest 24-hour PSI for each region.
+func (c *Client) PSI(ctx context.Context) (RegionReadings, error) {
+	return c.regionReadings(ctx, PSIProduct, "psi_twenty_four_hourly")
+}
+
+// PM25 returns the latest 1-hour PM2.5 concentration for each region, in µg/m³.
+func (c *Client) PM25(ctx context.Context) (RegionReadings, error) {
+	return c.regionReadings(ctx, PM25Product, "pm25_one_hourly")
+}
+
+func (c *Client) regionReadings(ctx context.Context, product, kind string) (RegionReadings, error) {
+	var r RegionReadings
+	err := c.get(ctx, product, func(body []byte) (err error) {
+		r, err = parseRegionReadings(body, kind)
+		return err
+	})
+	return r, err
+}
+
+// UV returns the latest UV index.
+func (c *Client) UV(ctx context.Context) (UV, error) {
+	var uv UV
+	err := c.get(ctx, UVProduct, func(body []byte) (err error) {
+		uv, err = parseUV(body)
+		return err
+	})
+	return uv, err
+}
@@ -0,0 +1,184 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer serves the products in testdata, counting the requests made
+func newTestServer(t *testing.T, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		body, err := ioutil.ReadFile(filepath.Join("testdata", strings.TrimPrefix(r.URL.Path, "/")+".json"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+}
+
+func TestTwoHour(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+	c := NewClient(server.URL+"/", "", time.Minute, time.Second)
+
+	f, err := c.TwoHour(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Areas) != 3 || len(f.Forecasts) != 3 {
+		t.Fatalf("expected 3 areas and forecasts, got %d and %d", len(f.Areas), len(f.Forecasts))
+	}
+	if f.ValidTo.Sub(f.ValidFrom) != 2*time.Hour {
+		t.Errorf("expected a 2 hour valid period, got %v to %v", f.ValidFrom, f.ValidTo)
+	}
+
+	// Cinnamon College is nearest to Clementi
+	area, forecast, ok := f.Nearest(1.3067, 103.7735)
+	if !ok || area.Name != "Clementi" || forecast != "Thundery Showers" {
+		t.Errorf("expected Clementi, Thundery Showers, got %s, %s, %v", area.Name, forecast, ok)
+	}
+}
+
+func TestDay(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+	c := NewClient(server.URL+"/", "", time.Minute, time.Second)
+
+	d, err := c.Day(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Forecast != "Thundery Showers" || d.Temperature != (Range{25, 33}) || d.Humidity != (Range{60, 95}) {
+		t.Errorf("unexpected general forecast %+v", d)
+	}
+	if len(d.Periods) != 2 || d.Periods[0].Regions[West] != "Thundery Showers" {
+		t.Errorf("unexpected periods %+v", d.Periods)
+	}
+}
+
+func TestFourDay(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+	c := NewClient(server.URL+"/", "", time.Minute, time.Second)
+
+	o, err := c.FourDay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o) != 4 {
+		t.Fatalf("expected 4 days, got %d", len(o))
+	}
+	if o[2].Date.Format(dateLayout) != "2020-09-04" || o[2].Forecast != "Fair and warm" || o[2].Temperature.High != 34 {
+		t.Errorf("unexpected outlook %+v", o[2])
+	}
+}
+
+func TestReadings(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+	c := NewClient(server.URL+"/", "", time.Minute, time.Second)
+
+	temp, err := c.Temperature(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if temp.Unit != "deg C" {
+		t.Errorf("expected unit deg C, got %s", temp.Unit)
+	}
+	station, value, ok := temp.Nearest(1.3067, 103.7735)
+	if !ok || station.ID != "S50" || value != 30.4 {
+		t.Errorf("expected S50 30.4, got %s %v %v", station.ID, value, ok)
+	}
+
+	// The nearest station without a reading is skipped
+	rain, err := c.Rainfall(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	station, value, ok = rain.Nearest(1.3067, 103.7735)
+	if !ok || station.ID != "S77" || value != 0.2 {
+		t.Errorf("expected S77 0.2, got %s %v %v", station.ID, value, ok)
+	}
+}
+
+func TestClientCaches(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+	c := NewClient(server.URL+"/", "", time.Minute, time.Second)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.TwoHour(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 request while cached, got %d", calls)
+	}
+
+	// cache expires after the ttl
+	now = now.Add(2 * time.Minute)
+	c.TwoHour(context.Background())
+	if calls != 2 {
+		t.Errorf("expected 2 requests after expiry, got %d", calls)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+
+	// Missing product
+	c := NewClient(server.URL+"/missing/", "", time.Minute, time.Second)
+	_, err := c.TwoHour(context.Background())
+	var werr *Error
+	if !errors.As(err, &werr) || !errors.Is(err, ErrUnavailable) || werr.Product != TwoHourProduct {
+		t.Errorf("expected ErrUnavailable for %s, got %v", TwoHourProduct, err)
+	}
+
+	// Response in the wrong format
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": []}`))
+	}))
+	defer bad.Close()
+	c = NewClient(bad.URL+"/", "", time.Minute, time.Second)
+	if _, err := c.FourDay(context.Background()); !errors.Is(err, ErrBadResponse) {
+		t.Errorf("expected ErrBadResponse, got %v", err)
+	}
+
+	// Timeout
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	c = NewClient(slow.URL+"/", "", time.Minute, 10*time.Millisecond)
+	if _, err := c.Temperature(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable after timeout, got %v", err)
+	}
+}
+
+func TestRegion(t *testing.T) {
+	if r := Region(1.4360, 103.7860); r != North {
+		t.Errorf("expected Woodlands to be in the north, got %s", r)
+	}
+	if r := Region(1.3521, 103.9448); r != East {
+		t.Errorf("expected Tampines to be in the east, got %s", r)
+	}
+}
@@ -0,0 +1,188 @@
+package weather
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// errNoItems is returned when a response has no forecasts or readings
+var errNoItems = errors.New("no items in response")
+
+type location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type validPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type rangeJSON struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+func (r rangeJSON) toRange() Range {
+	return Range{Low: r.Low, High: r.High}
+}
+
+// twoHourJSON is the format of the 2-hour weather forecast API
+type twoHourJSON struct {
+	AreaMetadata []struct {
+		Name          string   `json:"name"`
+		LabelLocation location `json:"label_location"`
+	} `json:"area_metadata"`
+	Items []struct {
+		ValidPeriod validPeriod `json:"valid_period"`
+		Forecasts   []struct {
+			Area     string `json:"area"`
+			Forecast string `json:"forecast"`
+		} `json:"forecasts"`
+	} `json:"items"`
+}
+
+func parseTwoHour(body []byte) (TwoHour, error) {
+	var raw twoHourJSON
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return TwoHour{}, err
+	}
+	if len(raw.Items) == 0 || len(raw.Items[0].Forecasts) == 0 {
+		return TwoHour{}, errNoItems
+	}
+
+	f := TwoHour{
+		Areas:     make([]Area, 0, len(raw.AreaMetadata)),
+		Forecasts: make(map[string]string),
+		ValidFrom: raw.Items[0].ValidPeriod.Start,
+		ValidTo:   raw.Items[0].ValidPeriod.End,
+	}
+	for _, a := range raw.AreaMetadata {
+		f.Areas = append(f.Areas, Area{a.Name, a.LabelLocation.Latitude, a.LabelLocation.Longitude})
+	}
+	for _, forecast := range raw.Items[0].Forecasts {
+		f.Forecasts[forecast.Area] = forecast.Forecast
+	}
+	return f, nil
+}
+
+// dayJSON is the format of the 24-hour weather forecast API
+type dayJSON struct {
+	Items []struct {
+		General struct {
+			Forecast         string    `json:"forecast"`
+			RelativeHumidity rangeJSON `json:"relative_humidity"`
+			Temperature      rangeJSON `json:"temperature"`
+		} `json:"general"`
+		Periods []struct {
+			Time    validPeriod       `json:"time"`
+			Regions map[string]string `json:"regions"`
+		} `json:"periods"`
+	} `json:"items"`
+}
+
+func parseDay(body []byte) (Day, error) {
+	var raw dayJSON
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return Day{}, err
+	}
+	if len(raw.Items) == 0 {
+		return Day{}, errNoItems
+	}
+
+	item := raw.Items[0]
+	d := Day{
+		Forecast:    item.General.Forecast,
+		Temperature: item.General.Temperature.toRange(),
+		Humidity:    item.General.RelativeHumidity.toRange(),
+		Periods:     make([]Period, 0, len(item.Periods)),
+	}
+	for _, p := range item.Periods {
+		d.Periods = append(d.Periods, Period{Start: p.Time.Start, End: p.Time.End, Regions: p.Regions})
+	}
+	return d, nil
+}
+
+// fourDayJSON is the format of the 4-day weather forecast API
+type fourDayJSON struct {
+	Items []struct {
+		Forecasts []struct {
+			Date             string    `json:"date"`
+			Forecast         string    `json:"forecast"`
+			RelativeHumidity rangeJSON `json:"relative_humidity"`
+			Temperature      rangeJSON `json:"temperature"`
+		} `json:"forecasts"`
+	} `json:"items"`
+}
+
+// dateLayout is the layout of the dates in the 4-day forecast
+const dateLayout = "2006-01-02"
+
+func parseFourDay(body []byte) ([]Outlook, error) {
+	var raw fourDayJSON
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw.Items) == 0 || len(raw.Items[0].Forecasts) == 0 {
+		return nil, errNoItems
+	}
+
+	outlook := make([]Outlook, 0, len(raw.Items[0].Forecasts))
+	for _, f := range raw.Items[0].Forecasts {
+		date, err := time.Parse(dateLayout, f.Date)
+		if err != nil {
+			return nil, err
+		}
+		outlook = append(outlook, Outlook{
+			Date:        date,
+			Forecast:    f.Forecast,
+			Temperature: f.Temperature.toRange(),
+			Humidity:    f.RelativeHumidity.toRange(),
+		})
+	}
+	return outlook, nil
+}
+
+// readingsJSON is the format of the real-time weather readings APIs
+type readingsJSON struct {
+	Metadata struct {
+		Stations []struct {
+			ID       string   `json:"id"`
+			Name     string   `json:"name"`
+			Location location `json:"location"`
+		} `json:"stations"`
+		ReadingUnit string `json:"reading_unit"`
+	} `json:"metadata"`
+	Items []struct {
+		Timestamp time.Time `json:"timestamp"`
+		Readings  []struct {
+			StationID string  `json:"station_id"`
+			Value     float64 `json:"value"`
+		} `json:"readings"`
+	} `json:"items"`
+}
+
+func parseReadings(body []byte) (Readings, error) {
+	var raw readingsJSON
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return Readings{}, err
+	}
+	if len(raw.Items) == 0 || len(raw.Items[0].Readings) == 0 {
+		return Readings{}, errNoItems
+	}
+
+	r := Readings{
+		Timestamp: raw.Items[0].Timestamp,
+		Unit:      raw.Metadata.ReadingUnit,
+		Stations:  make([]Station, 0, len(raw.Metadata.Stations)),
+		Values:    make(map[string]float64),
+	}
+	for _, s := range raw.Metadata.Stations {
+		r.Stations = append(r.Stations, Station{s.ID, s.Name, s.Location.Latitude, s.Location.Longitude})
+	}
+	for _, reading := range raw.Items[0].Readings {
+		r.Values[reading.StationID] = reading.Value
+	}
+	return r, nil
+}
@@ -0,0 +1,163 @@
+// Package weather fetches weather forecasts and readings from data.gov.sg.
+//
+// Client caches each product for a short time, as they are only updated every few minutes,
+// and returns an *Error if a product cannot be fetched or understood.
+package weather
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/usdevs/cinnabot/utils"
+)
+
+var (
+	// ErrUnavailable is the Kind of an Error when data.gov.sg could not be reached in time,
+	// or did not respond successfully.
+	ErrUnavailable = errors.New("weather unavailable")
+	// ErrBadResponse is the Kind of an Error when the response from data.gov.sg could not be understood.
+	ErrBadResponse = errors.New("unexpected weather data")
+)
+
+// Error is returned when a weather product cannot be fetched. errors.Is can be used to check its Kind.
+type Error struct {
+	Product string // eg. "2-hour-weather-forecast"
+	Kind    error  // ErrUnavailable or ErrBadResponse
+	Err     error  // the underlying cause
+}
+
+func (e *Error) Error() string {
+	return e.Product + ": " + e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the Kind of the error.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Area is a place in Singapore which forecasts are given for, eg. Clementi.
+type Area struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+// distanceTo returns the distance in metres from the area to a point
+func (a Area) distanceTo(lat, lng float64) float64 {
+	return utils.Haversine(a.Latitude, a.Longitude, lat, lng)
+}
+
+// nearest returns the index of the area nearest to a point, or -1 if there are no areas
+func nearest(areas []Area, lat, lng float64) int {
+	best, bestDistance := -1, math.Inf(1)
+	for i, a := range areas {
+		if d := a.distanceTo(lat, lng); d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+// TwoHour is the 2-hour weather forecast for each area of Singapore.
+type TwoHour struct {
+	Areas     []Area
+	Forecasts map[string]string // area name to forecast, eg. "Partly Cloudy (Day)"
+	ValidFrom time.Time
+	ValidTo   time.Time
+}
+
+// Nearest returns the area nearest to a point and its forecast.
+func (f TwoHour) Nearest(lat, lng float64) (Area, string, bool) {
+	i := nearest(f.Areas, lat, lng)
+	if i < 0 {
+		return Area{}, "", false
+	}
+	forecast, ok := f.Forecasts[f.Areas[i].Name]
+	return f.Areas[i], forecast, ok
+}
+
+// Range is a range of values, eg. of temperature.
+type Range struct {
+	Low  float64
+	High float64
+}
+
+// Regions of Singapore used by the 24-hour forecast and air quality readings
+const (
+	North   = "north"
+	South   = "south"
+	East    = "east"
+	West    = "west"
+	Central = "central"
+)
+
+// regionCentres are the locations of the regions, as given by data.gov.sg
+var regionCentres = []Area{
+	{North, 1.41803, 103.82},
+	{South, 1.29587, 103.82},
+	{East, 1.35735, 103.94},
+	{West, 1.35735, 103.7},
+	{Central, 1.35735, 103.82},
+}
+
+// Region returns the region of Singapore nearest to a point.
+func Region(lat, lng float64) string {
+	return regionCentres[nearest(regionCentres, lat, lng)].Name
+}
+
+// Period is part of a day in the 24-hour forecast.
+type Period struct {
+	Start   time.Time
+	End     time.Time
+	Regions map[string]string // region to forecast
+}
+
+// Day is the 24-hour weather forecast for Singapore.
+type Day struct {
+	Forecast    string
+	Temperature Range // °C
+	Humidity    Range // %
+	Periods     []Period
+}
+
+// Outlook is the forecast for one of the next 4 days.
+type Outlook struct {
+	Date        time.Time
+	Forecast    string
+	Temperature Range // °C
+	Humidity    Range // %
+}
+
+// Station is a weather station.
+type Station struct {
+	ID        string
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+// Readings are the latest readings from weather stations, eg. of temperature.
+type Readings struct {
+	Timestamp time.Time
+	Unit      string // eg. "deg C" or "mm"
+	Stations  []Station
+	Values    map[string]float64 // station ID to reading
+}
+
+// Nearest returns the reading of the station nearest to a point. Stations without a reading are skipped.
+func (r Readings) Nearest(lat, lng float64) (Station, float64, bool) {
+	areas := make([]Area, 0, len(r.Stations))
+	stations := make([]Station, 0, len(r.Stations))
+	for _, s := range r.Stations {
+		if _, ok := r.Values[s.ID]; ok {
+			areas = append(areas, Area{s.Name, s.Latitude, s.Longitude})
+			stations = append(stations, s)
+		}
+	}
+	i := nearest(areas, lat, lng)
+	if i < 0 {
+		return Station{}, 0, false
+	}
+	return stations[i], r.Values[stations[i].ID], true
+}
@@ -0,0 +1,201 @@
+package cinnabot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/usdevs/cinnabot/model"
+	"github.com/usdevs/cinnabot/weather"
+)
+
+var sgt = time.FixedZone("SGT", 8*60*60)
+
+func TestFormatWeatherNow(t *testing.T) {
+	forecast := weather.TwoHour{
+		Areas: []weather.Area{
+			{Name: "Clementi", Latitude: 1.315, Longitude: 103.76},
+			{Name: "Tampines", Latitude: 1.345, Longitude: 103.944},
+		},
+		Forecasts: map[string]string{"Clementi": "Partly Cloudy (Day)", "Tampines": "Thundery Showers"},
+		ValidTo:   time.Date(2020, 9, 1, 16, 0, 0, 0, sgt),
+	}
+	stations := []weather.Station{
+		{ID: "S50", Name: "Clementi Road", Latitude: 1.3337, Longitude: 103.7768},
+		{ID: "S107", Name: "East Coast Parkway", Latitude: 1.3135, Longitude: 103.9625},
+	}
+	temperature := weather.Readings{Stations: stations, Values: map[string]float64{"S50": 30.4, "S107": 29.8}}
+	rainfall := weather.Readings{Stations: stations, Values: map[string]float64{"S50": 0, "S107": 1.2}}
+
+	got := formatWeatherNow(forecast, temperature, rainfall, cinnamonLoc)
+	expected := "🤖: The 2h forecast is partly cloudy for Clementi, until 4pm\n\n" +
+		"🌡 30.4°C at Clementi Road\n" +
+		"🌤 No rain in the last 5 min at Clementi Road"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// Readings which could not be fetched are left out
+	got = formatWeatherNow(forecast, weather.Readings{}, weather.Readings{}, cinnamonLoc)
+	expected = "🤖: The 2h forecast is partly cloudy for Clementi, until 4pm"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestFormatWeatherToday(t *testing.T) {
+	day := weather.Day{
+		Forecast:    "Thundery Showers",
+		Temperature: weather.Range{Low: 25, High: 33},
+		Humidity:    weather.Range{Low: 60, High: 95},
+		Periods: []weather.Period{
+			{
+				Start:   time.Date(2020, 9, 1, 12, 0, 0, 0, sgt),
+				End:     time.Date(2020, 9, 1, 18, 0, 0, 0, sgt),
+				Regions: map[string]string{weather.West: "Thundery Showers", weather.East: "Cloudy"},
+			},
+			{
+				Start:   time.Date(2020, 9, 1, 18, 0, 0, 0, sgt),
+				End:     time.Date(2020, 9, 2, 6, 0, 0, 0, sgt),
+				Regions: map[string]string{weather.West: "Partly Cloudy (Night)", weather.East: "Cloudy"},
+			},
+		},
+	}
+	got := formatWeatherToday(day, weather.West)
+	expected := "🤖: *Next 24h*: Thundery Showers\n25–33°C, humidity 60–95%\n\n" +
+		"*West*\n12pm–6pm: Thundery Showers\n6pm–6am: Partly Cloudy (Night)"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestFormatWeatherOutlook(t *testing.T) {
+	outlook := []weather.Outlook{
+		{Date: time.Date(2020, 9, 2, 0, 0, 0, 0, time.UTC), Forecast: "Afternoon thundery showers", Temperature: weather.Range{Low: 25, High: 33}},
+		{Date: time.Date(2020, 9, 3, 0, 0, 0, 0, time.UTC), Forecast: "Fair and warm", Temperature: weather.Range{Low: 26, High: 26}},
+	}
+	got := formatWeatherOutlook(outlook)
+	expected := "🤖: *4-day outlook*\n\n*Wed 2 Sep*: Afternoon thundery showers, 25–33°C\n*Thu 3 Sep*: Fair and warm, 26°C"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestWeatherErrorStr(t *testing.T) {
+	bad := &weather.Error{Product: weather.FourDayProduct, Kind: weather.ErrBadResponse, Err: errors.New("no items")}
+	if got := weatherErrorStr(bad); got == weatherErrorStr(&weather.Error{Product: weather.FourDayProduct, Kind: weather.ErrUnavailable, Err: errors.New("timeout")}) {
+		t.Errorf("expected different messages for unavailable and bad responses, got %q", got)
+	}
+}
+
+func TestIsWet(t *testing.T) {
+	for _, f := range []string{"Thundery Showers", "Light Rain", "Passing Showers", "Heavy Thundery Showers with Gusty Winds", "Drizzle"} {
+		if !isWet(f) {
+			t.Errorf("expected %q to be wet", f)
+		}
+	}
+	for _, f := range []string{"Fair (Day)", "Partly Cloudy (Night)", "Cloudy", "Hazy", "Windy"} {
+		if isWet(f) {
+			t.Errorf("expected %q not to be wet", f)
+		}
+	}
+}
+
+func TestRainAlertsDue(t *testing.T) {
+	ra := newRainAlerts()
+	subscribers := map[int]string{1: "Queenstown", 2: "Clementi", 3: "Queenstown"}
+	now := time.Date(2020, 9, 1, 14, 0, 0, 0, sgt)
+
+	// Forecasts are only recorded by the first check
+	if got := ra.due(subscribers, map[string]string{"Queenstown": "Thundery Showers", "Clementi": "Fair (Day)"}, now); len(got) != 0 {
+		t.Errorf("expected no alerts on the first check, got %v", got)
+	}
+
+	now = now.Add(30 * time.Minute)
+	got := ra.due(subscribers, map[string]string{"Queenstown": "Showers", "Clementi": "Light Rain"}, now)
+	if !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("expected an alert for user 2 only, got %v", got)
+	}
+
+	// Already alerted users are not alerted again soon after
+	now = now.Add(30 * time.Minute)
+	ra.due(subscribers, map[string]string{"Queenstown": "Fair (Day)", "Clementi": "Cloudy"}, now)
+	now = now.Add(30 * time.Minute)
+	got = ra.due(subscribers, map[string]string{"Queenstown": "Showers", "Clementi": "Showers"}, now)
+	if !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("expected alerts for users 1 and 3, got %v", got)
+	}
+
+	now = now.Add(30 * time.Minute)
+	ra.due(subscribers, map[string]string{"Queenstown": "Fair (Day)", "Clementi": "Cloudy"}, now)
+	now = now.Add(3 * time.Hour)
+	got = ra.due(subscribers, map[string]string{"Queenstown": "Cloudy", "Clementi": "Thundery Showers"}, now)
+	if !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("expected an alert for user 2 after the cooldown, got %v", got)
+	}
+}
+
+func TestFormatAir(t *testing.T) {
+	psi := weather.RegionReadings{
+		Timestamp: time.Date(2020, 9, 1, 14, 0, 0, 0, sgt),
+		Values:    map[string]float64{weather.West: 112, weather.National: 105},
+	}
+	pm25 := weather.RegionReadings{Values: map[string]float64{weather.West: 60}}
+	uv := weather.UV{Timestamp: time.Date(2020, 9, 1, 14, 0, 0, 0, sgt), Index: 7}
+
+	got := formatAir(weather.West, psi, pm25, uv)
+	expected := "🤖: *Air quality in the West region*\n" +
+		"🌫 PSI (24h): *112* · Unhealthy\n" +
+		"😷 PM2.5 (1h): *60* µg/m³ · Elevated\n" +
+		"☀️ UV index: *7* · High\n\n" +
+		"Reduce prolonged or strenuous outdoor activity. Use sunscreen and wear a hat.\n\n" +
+		"Last updated: 2pm"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// Regions without readings fall back to the national reading
+	got = formatAir(weather.East, psi, weather.RegionReadings{}, weather.UV{})
+	expected = "🤖: *Air quality in Singapore*\n" +
+		"🌫 PSI (24h): *105* · Unhealthy\n\n" +
+		"Reduce prolonged or strenuous outdoor activity.\n\n" +
+		"Last updated: 2pm"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// No reading is not good air quality
+	psi.Values = map[string]float64{weather.West: 112}
+	if got = formatAir(weather.East, psi, pm25, uv); got != noPSIStr {
+		t.Errorf("expected %q without a reading, got:\n%s", noPSIStr, got)
+	}
+}
+
+func TestAirAlertsDue(t *testing.T) {
+	aa := newAirAlerts()
+	alerts := []model.AirAlert{
+		{UserID: 1, Region: weather.West, Threshold: 101},
+		{UserID: 2, Region: weather.East, Threshold: 51},
+	}
+
+	// Readings are only recorded by the first check
+	if got := aa.due(alerts, map[string]float64{weather.West: 90, weather.East: 60}); len(got) != 0 {
+		t.Errorf("expected no alerts on the first check, got %v", got)
+	}
+
+	got := aa.due(alerts, map[string]float64{weather.West: 101, weather.East: 70})
+	if len(got) != 1 || got[0].UserID != 1 {
+		t.Errorf("expected an alert for user 1 only, got %v", got)
+	}
+
+	// Alerts fire again only after the PSI drops below the threshold
+	if got := aa.due(alerts, map[string]float64{weather.West: 120, weather.East: 70}); len(got) != 0 {
+		t.Errorf("expected no alerts while the PSI stays high, got %v", got)
+	}
+	aa.due(alerts, map[string]float64{weather.West: 95, weather.East: 70})
+	got = aa.due(alerts, map[string]float64{weather.West: 105, weather.East: 70})
+	if len(got) != 1 || got[0].UserID != 1 {
+		t.Errorf("expected another alert for user 1, got %v", got)
+	}
+}